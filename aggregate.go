@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// Aggregate は複数セッションを集計した値
+type Aggregate struct {
+	Sessions             int     `json:"sessions"`
+	Completed            int     `json:"completed"`       // result を受信したセッション数
+	FailedSessions       int     `json:"failed_sessions"` // result が is_error のセッション数
+	TotalCostUsd         float64 `json:"total_cost_usd"`
+	AvgCostUsd           float64 `json:"avg_cost_usd"`
+	TotalDurationMs      int     `json:"total_duration_ms"`
+	AvgDurationMs        float64 `json:"avg_duration_ms"`
+	TotalTurns           int     `json:"total_turns"`
+	AvgTurns             float64 `json:"avg_turns"`
+	ToolCalls            int     `json:"tool_calls"`
+	ToolErrors           int     `json:"tool_errors"`
+	PermissionDenials    int     `json:"permission_denials"`
+	ErrorRate            float64 `json:"error_rate"`             // ツール呼び出しに対するエラーの割合
+	PermissionDenialRate float64 `json:"permission_denial_rate"` // ツール呼び出しに対するパーミッション拒否の割合
+}
+
+// StatsReport は stats サブコマンドの集計結果
+type StatsReport struct {
+	Summary   Aggregate             `json:"summary"`
+	Models    map[string]ModelUsage `json:"models"`
+	Tools     map[string]int        `json:"tools"`
+	ByProject map[string]*Aggregate `json:"by_project"`
+	ByModel   map[string]*Aggregate `json:"by_model"`
+	ByVersion map[string]*Aggregate `json:"by_version"`
+}
+
+// newStatsReport は空の StatsReport を作成
+func newStatsReport() *StatsReport {
+	return &StatsReport{
+		Models:    make(map[string]ModelUsage),
+		Tools:     make(map[string]int),
+		ByProject: make(map[string]*Aggregate),
+		ByModel:   make(map[string]*Aggregate),
+		ByVersion: make(map[string]*Aggregate),
+	}
+}
+
+// add は1セッション分を集計に加える
+func (r *StatsReport) add(t *Tracker) {
+	stats := computeStats(t)
+
+	project, model, version := "(unknown)", "(unknown)", "(unknown)"
+	if t.Init != nil {
+		project = orUnknown(t.Init.Cwd)
+		model = orUnknown(t.Init.Model)
+		version = orUnknown(t.Init.ClaudeCodeVersion)
+	}
+
+	r.Summary.add(t.Result, stats)
+	groupAdd(r.ByProject, project, t.Result, stats)
+	groupAdd(r.ByModel, model, t.Result, stats)
+	groupAdd(r.ByVersion, version, t.Result, stats)
+
+	for name, usage := range stats.Models {
+		total := r.Models[name]
+		total.InputTokens += usage.InputTokens
+		total.OutputTokens += usage.OutputTokens
+		total.CacheReadInputTokens += usage.CacheReadInputTokens
+		total.CacheCreationInputTokens += usage.CacheCreationInputTokens
+		total.CostUSD += usage.CostUSD
+		r.Models[name] = total
+	}
+	for name, n := range stats.ToolCalls {
+		r.Tools[name] += n
+	}
+}
+
+// finish は平均と割合を計算
+func (r *StatsReport) finish() {
+	r.Summary.finish()
+	for _, groups := range []map[string]*Aggregate{r.ByProject, r.ByModel, r.ByVersion} {
+		for _, a := range groups {
+			a.finish()
+		}
+	}
+}
+
+// add は1セッション分を加算
+func (a *Aggregate) add(result *ResultMessage, stats Stats) {
+	a.Sessions++
+	for _, n := range stats.ToolCalls {
+		a.ToolCalls += n
+	}
+	a.ToolErrors += stats.Errors
+	a.PermissionDenials += stats.PermissionDenials
+
+	if result == nil {
+		return
+	}
+	a.Completed++
+	if result.IsError {
+		a.FailedSessions++
+	}
+	a.TotalCostUsd += result.TotalCostUsd
+	a.TotalDurationMs += result.DurationMs
+	a.TotalTurns += result.NumTurns
+}
+
+// finish は平均と割合を計算 (平均は result を受信したセッションのみが対象)
+func (a *Aggregate) finish() {
+	if a.Completed > 0 {
+		n := float64(a.Completed)
+		a.AvgCostUsd = a.TotalCostUsd / n
+		a.AvgDurationMs = float64(a.TotalDurationMs) / n
+		a.AvgTurns = float64(a.TotalTurns) / n
+	}
+	if a.ToolCalls > 0 {
+		a.ErrorRate = float64(a.ToolErrors) / float64(a.ToolCalls)
+		a.PermissionDenialRate = float64(a.PermissionDenials) / float64(a.ToolCalls)
+	}
+}
+
+// groupAdd はグループごとの集計に1セッション分を加算
+func groupAdd(groups map[string]*Aggregate, key string, result *ResultMessage, stats Stats) {
+	a := groups[key]
+	if a == nil {
+		a = &Aggregate{}
+		groups[key] = a
+	}
+	a.add(result, stats)
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "(unknown)"
+	}
+	return s
+}
+
+// runStatsCommand は "ccfilter stats" サブコマンドを実行
+func runStatsCommand(args []string, output io.Writer) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	fs.Usage = printStatsHelp
+	format := fs.String("format", "table", "Output format (table|csv|json)")
+
+	paths, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if *format != "table" && *format != "csv" && *format != "json" {
+		return fmt.Errorf("invalid format: %s (must be table, csv, or json)", *format)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("stats requires at least one DIR or FILE")
+	}
+
+	files, err := collectLogFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	report := newStatsReport()
+	for _, file := range files {
+		tracker, err := trackLogFile(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		report.add(tracker)
+	}
+	report.finish()
+
+	switch *format {
+	case "csv":
+		return writeStatsCSV(output, report)
+	case "json":
+		enc := json.NewEncoder(output)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	default:
+		return writeStatsTable(output, report)
+	}
+}
+
+// collectLogFiles は引数のファイルと、ディレクトリ以下の .json/.jsonl/.ndjson ファイルを集める
+func collectLogFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			switch filepath.Ext(p) {
+			case ".json", ".jsonl", ".ndjson":
+				if !d.IsDir() {
+					files = append(files, p)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// trackLogFile は1つのログファイル (1セッション) を読んで Tracker に記録
+func trackLogFile(path string) (*Tracker, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tracker := newTracker()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line, at := scanner.Text(), time.Time{}
+		if env, ok := parseEnvelope(line); ok {
+			line, at = string(env.Line), env.Time
+		}
+		msg, err := parseMessage(line)
+		if err != nil {
+			continue
+		}
+		tracker.observe(msg.Type, []byte(line), at)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	tracker.close()
+	return tracker, nil
+}
+
+// writeStatsTable は集計結果を端末向けの表で出力
+func writeStatsTable(output io.Writer, r *StatsReport) error {
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	s := r.Summary
+
+	fmt.Fprintf(w, "Sessions:\t%d (completed %d, failed %d)\n", s.Sessions, s.Completed, s.FailedSessions)
+	fmt.Fprintf(w, "Cost:\t$%.4f total, $%.4f avg\n", s.TotalCostUsd, s.AvgCostUsd)
+	fmt.Fprintf(w, "Duration:\t%.1fs total, %.1fs avg\n", float64(s.TotalDurationMs)/1000.0, s.AvgDurationMs/1000.0)
+	fmt.Fprintf(w, "Turns:\t%d total, %.1f avg\n", s.TotalTurns, s.AvgTurns)
+	fmt.Fprintf(w, "Tool calls:\t%d (errors %d = %.1f%%, permission denials %d = %.1f%%)\n",
+		s.ToolCalls, s.ToolErrors, s.ErrorRate*100, s.PermissionDenials, s.PermissionDenialRate*100)
+
+	fmt.Fprintf(w, "\nMODEL\tINPUT\tOUTPUT\tCACHE READ\tCACHE WRITE\tCOST\n")
+	for _, name := range sortedKeys(r.Models) {
+		m := r.Models[name]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t$%.4f\n", name, m.InputTokens, m.OutputTokens, m.CacheReadInputTokens, m.CacheCreationInputTokens, m.CostUSD)
+	}
+
+	fmt.Fprintf(w, "\nTOOL\tCALLS\n")
+	for _, name := range sortedByCount(r.Tools) {
+		fmt.Fprintf(w, "%s\t%d\n", name, r.Tools[name])
+	}
+
+	for _, group := range []struct {
+		title  string
+		groups map[string]*Aggregate
+	}{
+		{"PROJECT", r.ByProject},
+		{"MODEL", r.ByModel},
+		{"VERSION", r.ByVersion},
+	} {
+		fmt.Fprintf(w, "\n%s\tSESSIONS\tCOST\tAVG COST\tAVG DURATION\tAVG TURNS\tERROR RATE\tDENIAL RATE\n", group.title)
+		for _, key := range sortedKeys(group.groups) {
+			a := group.groups[key]
+			fmt.Fprintf(w, "%s\t%d\t$%.4f\t$%.4f\t%.1fs\t%.1f\t%.1f%%\t%.1f%%\n",
+				key, a.Sessions, a.TotalCostUsd, a.AvgCostUsd, a.AvgDurationMs/1000.0, a.AvgTurns, a.ErrorRate*100, a.PermissionDenialRate*100)
+		}
+	}
+
+	return w.Flush()
+}
+
+// writeStatsCSV は集計結果を section,key,metric,value の縦持ちCSVで出力
+func writeStatsCSV(output io.Writer, r *StatsReport) error {
+	w := csv.NewWriter(output)
+	rows := [][]string{{"section", "key", "metric", "value"}}
+
+	rows = append(rows, aggregateRows("summary", "all", r.Summary)...)
+	for _, name := range sortedKeys(r.Models) {
+		m := r.Models[name]
+		rows = append(rows,
+			[]string{"model_tokens", name, "input_tokens", strconv.Itoa(m.InputTokens)},
+			[]string{"model_tokens", name, "output_tokens", strconv.Itoa(m.OutputTokens)},
+			[]string{"model_tokens", name, "cache_read_input_tokens", strconv.Itoa(m.CacheReadInputTokens)},
+			[]string{"model_tokens", name, "cache_creation_input_tokens", strconv.Itoa(m.CacheCreationInputTokens)},
+			[]string{"model_tokens", name, "cost_usd", formatFloat(m.CostUSD)},
+		)
+	}
+	for _, name := range sortedKeys(r.Tools) {
+		rows = append(rows, []string{"tools", name, "calls", strconv.Itoa(r.Tools[name])})
+	}
+	for _, group := range []struct {
+		section string
+		groups  map[string]*Aggregate
+	}{
+		{"project", r.ByProject},
+		{"model", r.ByModel},
+		{"version", r.ByVersion},
+	} {
+		for _, key := range sortedKeys(group.groups) {
+			rows = append(rows, aggregateRows(group.section, key, *group.groups[key])...)
+		}
+	}
+
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// aggregateRows は Aggregate を縦持ちCSVの行に変換
+func aggregateRows(section, key string, a Aggregate) [][]string {
+	row := func(metric, value string) []string {
+		return []string{section, key, metric, value}
+	}
+	return [][]string{
+		row("sessions", strconv.Itoa(a.Sessions)),
+		row("completed", strconv.Itoa(a.Completed)),
+		row("failed_sessions", strconv.Itoa(a.FailedSessions)),
+		row("total_cost_usd", formatFloat(a.TotalCostUsd)),
+		row("avg_cost_usd", formatFloat(a.AvgCostUsd)),
+		row("total_duration_ms", strconv.Itoa(a.TotalDurationMs)),
+		row("avg_duration_ms", formatFloat(a.AvgDurationMs)),
+		row("total_turns", strconv.Itoa(a.TotalTurns)),
+		row("avg_turns", formatFloat(a.AvgTurns)),
+		row("tool_calls", strconv.Itoa(a.ToolCalls)),
+		row("tool_errors", strconv.Itoa(a.ToolErrors)),
+		row("permission_denials", strconv.Itoa(a.PermissionDenials)),
+		row("error_rate", formatFloat(a.ErrorRate)),
+		row("permission_denial_rate", formatFloat(a.PermissionDenialRate)),
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// sortedByCount は回数の多い順 (同数は名前順) にキーを返す
+func sortedByCount(m map[string]int) []string {
+	keys := sortedKeys(m)
+	sort.SliceStable(keys, func(i, j int) bool {
+		return m[keys[i]] > m[keys[j]]
+	})
+	return keys
+}
+
+// printStatsHelp は stats サブコマンドのヘルプを表示
+func printStatsHelp() {
+	fmt.Fprint(os.Stderr, `Usage: ccfilter stats [--format=table|csv|json] DIR|FILE ...
+
+Aggregate many stream-json session logs (one session per file).
+Directories are searched recursively for *.json, *.jsonl and *.ndjson files.
+
+Options:
+  --format=FORMAT   Output format (table|csv|json) [default: table]
+`)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const aggregateSessionA = `{"type":"system","subtype":"init","cwd":"/work/api","session_id":"a","model":"sonnet","claude_code_version":"2.0.21"}
+{"type":"assistant","message":{"id":"m1","model":"sonnet","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"ls"}},{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"a.go"}}]}}
+{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"a.go"},{"type":"tool_result","tool_use_id":"t2","content":"denied","is_error":true}]}}
+{"type":"result","subtype":"success","result":"ok","duration_ms":2000,"total_cost_usd":0.02,"num_turns":2,"session_id":"a","modelUsage":{"sonnet":{"inputTokens":10,"outputTokens":20,"costUSD":0.02}},"permission_denials":[{"tool_name":"Read","tool_use_id":"t2"}]}
+`
+
+const aggregateSessionB = `{"type":"system","subtype":"init","cwd":"/work/web","session_id":"b","model":"opus","claude_code_version":"2.0.22"}
+{"type":"assistant","message":{"id":"m1","model":"opus","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"make"}}]}}
+{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}}
+{"type":"result","subtype":"error","is_error":true,"result":"failed","duration_ms":4000,"total_cost_usd":0.04,"num_turns":4,"session_id":"b","modelUsage":{"opus":{"inputTokens":30,"outputTokens":40,"costUSD":0.04}}}
+`
+
+// writeAggregateLogs は集計用のログを一時ディレクトリに書き出す
+func writeAggregateLogs(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.jsonl"), aggregateSessionA)
+	writeFile(t, filepath.Join(dir, "nested", "b.json"), aggregateSessionB)
+	writeFile(t, filepath.Join(dir, "notes.txt"), "not a log")
+	return dir
+}
+
+func TestRunStatsCommand_JSON(t *testing.T) {
+	dir := writeAggregateLogs(t)
+
+	var output bytes.Buffer
+	if err := runStatsCommand([]string{dir, "--format=json"}, &output); err != nil {
+		t.Fatalf("runStatsCommand() error = %v", err)
+	}
+
+	var report StatsReport
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, output.String())
+	}
+
+	s := report.Summary
+	if s.Sessions != 2 || s.Completed != 2 || s.FailedSessions != 1 {
+		t.Errorf("Summary sessions = %+v", s)
+	}
+	if s.TotalCostUsd != 0.06 || s.AvgDurationMs != 3000 || s.AvgTurns != 3 {
+		t.Errorf("Summary totals = %+v", s)
+	}
+	if s.ToolCalls != 3 || s.ToolErrors != 1 || s.PermissionDenials != 1 {
+		t.Errorf("Summary tools = %+v", s)
+	}
+	if report.Tools["Bash"] != 2 || report.Tools["Read"] != 1 {
+		t.Errorf("Tools = %v", report.Tools)
+	}
+	if report.Models["opus"].OutputTokens != 40 || report.Models["sonnet"].InputTokens != 10 {
+		t.Errorf("Models = %+v", report.Models)
+	}
+	if report.ByProject["/work/api"].Sessions != 1 || report.ByProject["/work/web"].FailedSessions != 1 {
+		t.Errorf("ByProject = %+v", report.ByProject)
+	}
+	if report.ByModel["sonnet"].ErrorRate != 0.5 {
+		t.Errorf("ByModel[sonnet].ErrorRate = %v, want 0.5", report.ByModel["sonnet"].ErrorRate)
+	}
+	if report.ByVersion["2.0.22"].TotalCostUsd != 0.04 {
+		t.Errorf("ByVersion = %+v", report.ByVersion)
+	}
+}
+
+func TestRunStatsCommand_Table(t *testing.T) {
+	dir := writeAggregateLogs(t)
+
+	var output bytes.Buffer
+	if err := runStatsCommand([]string{dir}, &output); err != nil {
+		t.Fatalf("runStatsCommand() error = %v", err)
+	}
+
+	got := output.String()
+	for _, want := range []string{
+		"Sessions:    2 (completed 2, failed 1)",
+		"$0.0600 total, $0.0300 avg",
+		"PROJECT",
+		"/work/api",
+		"VERSION",
+		"2.0.22",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("table output does not contain %q\nGot: %s", want, got)
+		}
+	}
+}
+
+func TestRunStatsCommand_CSV(t *testing.T) {
+	dir := writeAggregateLogs(t)
+
+	var output bytes.Buffer
+	if err := runStatsCommand([]string{"--format", "csv", filepath.Join(dir, "a.jsonl")}, &output); err != nil {
+		t.Fatalf("runStatsCommand() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&output).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+	if strings.Join(records[0], ",") != "section,key,metric,value" {
+		t.Errorf("header = %v", records[0])
+	}
+
+	found := false
+	for _, r := range records {
+		if r[0] == "project" && r[1] == "/work/api" && r[2] == "permission_denial_rate" {
+			found = r[3] == "0.5"
+		}
+	}
+	if !found {
+		t.Errorf("permission_denial_rate row for /work/api not found or wrong\n%v", records)
+	}
+}
+
+func TestRunStatsCommand_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "no paths", args: []string{}},
+		{name: "invalid format", args: []string{"--format=xml", "."}},
+		{name: "missing path", args: []string{filepath.Join(t.TempDir(), "missing")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			if err := runStatsCommand(tt.args, &output); err == nil {
+				t.Error("runStatsCommand() should return error")
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// homePattern はホームディレクトリのパス (/home/NAME, /Users/NAME, C:\Users\NAME) とユーザー名
+var homePattern = regexp.MustCompile(`(?:/home/|/Users/|\b[A-Za-z]:\\Users\\)([A-Za-z0-9._-]+)`)
+
+// sharedHomeNames はユーザー名として扱わない名前 (共有のディレクトリと root)
+var sharedHomeNames = map[string]bool{"Shared": true, "Guest": true, "root": true}
+
+// idKeys は仮名に置き換える ID のキー
+// 文字列の中に現れる ID は UUID の形のもの (ログファイル名の session_id など) だけを置き換える
+var idKeys = map[string]bool{"session_id": true, "uuid": true, "parent_uuid": true}
+
+// uuidPattern は文字列の中の UUID
+var uuidPattern = regexp.MustCompile(`[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}`)
+
+// anonymizer は共有用にトランスクリプトから個人を特定できる情報を取り除く
+//
+//   - ホームディレクトリのパスの中のユーザー名を /home/user1 のような仮名にする (値全体がユーザー名の場合も user1 にする)
+//   - session_id と uuid を UUID の形の仮名にする (同じ値には常に同じ仮名を使う)
+//   - dropResults の場合はツール結果の本文をバイト数だけの表示にする
+type anonymizer struct {
+	dropResults bool
+
+	mu    sync.Mutex
+	users map[string]string // ユーザー名 → 仮名
+	homes map[string]string // 標準の場所にないホームディレクトリ → 仮名のパス
+	ids   map[string]string // ID → 仮名
+
+	homeReplacer *strings.Replacer // homes を長いものから置き換える (増えるたびに作り直す)
+}
+
+// newAnonymizer は anonymizer を作成
+func newAnonymizer(dropResults bool) *anonymizer {
+	return &anonymizer{
+		dropResults: dropResults,
+		users:       make(map[string]string),
+		homes:       make(map[string]string),
+		ids:         make(map[string]string),
+	}
+}
+
+// addUser はユーザー名を仮名にする対象に加える
+func (a *anonymizer) addUser(name string) string {
+	if name == "" || sharedHomeNames[name] {
+		return ""
+	}
+	if p, ok := a.users[name]; ok {
+		return p
+	}
+	p := fmt.Sprintf("user%d", len(a.users)+1)
+	a.users[name] = p
+	return p
+}
+
+// addHome はホームディレクトリを仮名にする対象に加える
+// /home/NAME のような標準の場所はユーザー名の置き換えで足りるので、それ以外の場所だけを覚える
+func (a *anonymizer) addHome(home string) {
+	home = strings.TrimRight(home, `/\`)
+	if home == "" || home == "/" {
+		return
+	}
+	if m := homePattern.FindStringSubmatch(home); m != nil && m[0] == home {
+		a.addUser(m[1])
+		return
+	}
+	user := a.addUser(filepath.Base(home))
+	if user == "" {
+		return
+	}
+	a.homes[home] = "/home/" + user
+	a.homeReplacer = nil
+}
+
+// anonymizeLine は JSON 行を匿名化する
+// 置き換える値がない場合や JSON として解析できない場合は元の行を返す
+func (a *anonymizer) anonymizeLine(line string) string {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return line
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	changed := false
+	if a.dropResults {
+		value = a.dropValue(value, true, &changed)
+	}
+	// 文字列の中に現れるユーザー名や ID も置き換えられるよう、先に行全体から集める
+	a.collect(value, "")
+	value = a.rewriteValue(value, &changed)
+	if !changed {
+		return line
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return line
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// collect は JSON の値からユーザー名と ID を集める
+func (a *anonymizer) collect(value interface{}, key string) {
+	switch v := value.(type) {
+	case string:
+		if idKeys[key] && v != "" {
+			a.pseudonym(v)
+		}
+		for _, m := range homePattern.FindAllStringSubmatch(v, -1) {
+			a.addUser(m[1])
+		}
+	case []interface{}:
+		for _, item := range v {
+			a.collect(item, key)
+		}
+	case map[string]interface{}:
+		// 仮名の番号が実行ごとに変わらないよう、キーの順に処理する
+		for _, k := range sortedKeys(v) {
+			a.collect(v[k], k)
+		}
+	}
+}
+
+// pseudonym は ID に対応する仮名を返す (初めての値には次の番号を割り当てる)
+// 複数入力のラベルは session_id の先頭8文字なので、番号は先頭に置く
+func (a *anonymizer) pseudonym(id string) string {
+	if p, ok := a.ids[id]; ok {
+		return p
+	}
+	p := fmt.Sprintf("%08x-0000-4000-8000-000000000000", len(a.ids)+1)
+	a.ids[id] = p
+	return p
+}
+
+// rewriteValue は JSON の値の中の文字列を再帰的に置き換える
+func (a *anonymizer) rewriteValue(value interface{}, changed *bool) interface{} {
+	switch v := value.(type) {
+	case string:
+		rewritten := a.rewrite(v)
+		if rewritten != v {
+			*changed = true
+		}
+		return rewritten
+	case []interface{}:
+		for i := range v {
+			v[i] = a.rewriteValue(v[i], changed)
+		}
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			v[k] = a.rewriteValue(v[k], changed)
+		}
+	}
+	return value
+}
+
+// rewrite は文字列中の ID・ホームディレクトリ・ユーザー名を仮名に置き換える
+// ユーザー名は "npm run dev" の dev のように別の意味の単語でもあるので、
+// ホームディレクトリのパスの中と、文字列全体がユーザー名の場合だけ置き換える
+func (a *anonymizer) rewrite(s string) string {
+	if p, ok := a.users[s]; ok {
+		return p
+	}
+	if p, ok := a.ids[s]; ok {
+		return p
+	}
+	// ID は行ごとに増えるので、既知の ID をすべて探すのではなく UUID の形の部分だけを引く
+	if len(a.ids) > 0 && strings.Contains(s, "-") {
+		s = uuidPattern.ReplaceAllStringFunc(s, func(id string) string {
+			if p, ok := a.ids[id]; ok {
+				return p
+			}
+			return id
+		})
+	}
+	if len(a.homes) > 0 {
+		if a.homeReplacer == nil {
+			a.homeReplacer = a.newHomeReplacer()
+		}
+		s = a.homeReplacer.Replace(s)
+	}
+
+	var output strings.Builder
+	last := 0
+	for _, m := range homePattern.FindAllStringSubmatchIndex(s, -1) {
+		p, ok := a.users[s[m[2]:m[3]]]
+		if !ok {
+			continue
+		}
+		output.WriteString(s[last:m[2]])
+		output.WriteString(p)
+		last = m[3]
+	}
+	if last == 0 {
+		return s
+	}
+	output.WriteString(s[last:])
+	return output.String()
+}
+
+// newHomeReplacer は標準の場所にないホームディレクトリを仮名のパスに置き換える Replacer を作成
+// 同じ位置では先に渡したものが優先されるので、長いものから渡して短いパスが長いパスの一部を壊さないようにする
+func (a *anonymizer) newHomeReplacer() *strings.Replacer {
+	var oldnew []string
+	for _, home := range longestFirst(a.homes) {
+		oldnew = append(oldnew, home, a.homes[home])
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+// dropValue はツール結果の本文を省略した旨の表示に置き換える
+// top は行の最上位の値かどうか (最上位の tool_use_result もツール結果の本文なので取り除く)
+func (a *anonymizer) dropValue(value interface{}, top bool, changed *bool) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		for i := range v {
+			v[i] = a.dropValue(v[i], false, changed)
+		}
+	case map[string]interface{}:
+		if top {
+			if _, ok := v["tool_use_result"]; ok {
+				delete(v, "tool_use_result")
+				*changed = true
+			}
+		}
+		if v["type"] == "tool_result" {
+			if _, ok := v["content"]; ok {
+				v["content"] = fmt.Sprintf("[result omitted: %d bytes]", resultSize(v))
+				*changed = true
+			}
+			return v
+		}
+		for k := range v {
+			v[k] = a.dropValue(v[k], false, changed)
+		}
+	}
+	return value
+}
+
+// resultSize はツール結果の本文のバイト数を返す (テキストのブロックは改行でつないだ長さ)
+func resultSize(block map[string]interface{}) int {
+	data, err := json.Marshal(block)
+	if err != nil {
+		return 0
+	}
+	var result ToolResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return 0
+	}
+	return len(result.Content)
+}
+
+// longestFirst は置き換え対象を長いものから順に並べて返す (短い値が長い値の一部を壊さないようにする)
+func longestFirst(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
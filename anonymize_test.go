@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAnonymizer_Rewrite(t *testing.T) {
+	tests := []struct {
+		name  string
+		homes []string
+		line  string
+		want  string
+	}{
+		{
+			name: "linux home in cwd",
+			line: `{"type":"system","subtype":"init","cwd":"/home/pankona/src/ccfilter"}`,
+			want: `{"cwd":"/home/user1/src/ccfilter","subtype":"init","type":"system"}`,
+		},
+		{
+			name: "macos home in file path and text",
+			line: `{"input":{"file_path":"/Users/alice/hello.go"},"text":"wrote /Users/alice/hello.go"}`,
+			want: `{"input":{"file_path":"/Users/user1/hello.go"},"text":"wrote /Users/user1/hello.go"}`,
+		},
+		{
+			name: "windows home",
+			line: `{"cwd":"C:\\Users\\bob\\work"}`,
+			want: `{"cwd":"C:\\Users\\user1\\work"}`,
+		},
+		{
+			name:  "home outside the usual places",
+			homes: []string{"/var/lib/carol"},
+			line:  `{"command":"ls /var/lib/carol/.cache","user":"carol"}`,
+			want:  `{"command":"ls /home/user1/.cache","user":"user1"}`,
+		},
+		{
+			name: "usernames only in paths and whole values",
+			line: `{"command":"npm run dev","cwd":"/home/dev","text":"cd /home/dev/app && ls /home/developer","user":"dev"}`,
+			want: `{"command":"npm run dev","cwd":"/home/user1","text":"cd /home/user1/app && ls /home/user2","user":"user1"}`,
+		},
+		{
+			name: "shared directories are kept",
+			line: `{"cwd":"/Users/Shared/project"}`,
+			want: `{"cwd":"/Users/Shared/project"}`,
+		},
+		{
+			name: "nothing to replace keeps the line",
+			line: `{"type":"result",  "num_turns":3}`,
+			want: `{"type":"result",  "num_turns":3}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAnonymizer(false)
+			for _, home := range tt.homes {
+				a.addHome(home)
+			}
+			if got := a.anonymizeLine(tt.line); got != tt.want {
+				t.Errorf("anonymizeLine() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnonymizer_ConsistentIDs(t *testing.T) {
+	a := newAnonymizer(false)
+	lines := []string{
+		`{"type":"system","session_id":"8f2c1e6a-aaaa-4bbb-8ccc-123456789abc","uuid":"u-1"}`,
+		`{"type":"assistant","session_id":"8f2c1e6a-aaaa-4bbb-8ccc-123456789abc","uuid":"u-2","parent_uuid":"u-1"}`,
+		`{"type":"result","session_id":"8f2c1e6a-aaaa-4bbb-8ccc-123456789abc","result":"log: ~/.claude/8f2c1e6a-aaaa-4bbb-8ccc-123456789abc.jsonl"}`,
+	}
+	want := []string{
+		`{"session_id":"00000001-0000-4000-8000-000000000000","type":"system","uuid":"00000002-0000-4000-8000-000000000000"}`,
+		`{"parent_uuid":"00000002-0000-4000-8000-000000000000","session_id":"00000001-0000-4000-8000-000000000000","type":"assistant","uuid":"00000003-0000-4000-8000-000000000000"}`,
+		`{"result":"log: ~/.claude/00000001-0000-4000-8000-000000000000.jsonl","session_id":"00000001-0000-4000-8000-000000000000","type":"result"}`,
+	}
+
+	for i, line := range lines {
+		if got := a.anonymizeLine(line); got != want[i] {
+			t.Errorf("line %d = %s, want %s", i+1, got, want[i])
+		}
+	}
+}
+
+func TestAnonymizer_DropResults(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "string content",
+			line: `{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"hello"}]}}`,
+			want: `{"message":{"content":[{"content":"[result omitted: 5 bytes]","tool_use_id":"t1","type":"tool_result"}]},"type":"user"}`,
+		},
+		{
+			name: "block content and tool_use_result",
+			line: `{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":[{"type":"text","text":"hi"}]}]},"tool_use_result":{"stdout":"hi"}}`,
+			want: `{"message":{"content":[{"content":"[result omitted: 2 bytes]","tool_use_id":"t1","type":"tool_result"}]},"type":"user"}`,
+		},
+		{
+			name: "tool input is kept",
+			line: `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"ls"}}]}}`,
+			want: `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"ls"}}]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAnonymizer(true)
+			if got := a.anonymizeLine(tt.line); got != tt.want {
+				t.Errorf("anonymizeLine() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessInput_Anonymize(t *testing.T) {
+	input := strings.Join([]string{
+		`{"type":"system","subtype":"init","cwd":"/home/pankona/src/ccfilter","session_id":"sess-1","tools":[],"model":"claude"}`,
+		`{"type":"assistant","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/home/pankona/src/ccfilter/hello.go"}}]},"session_id":"sess-1"}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"built by /home/pankona/sdk/go"}]},"session_id":"sess-1"}`,
+		`{"type":"result","subtype":"success","result":"done","session_id":"sess-1","num_turns":1}`,
+	}, "\n") + "\n"
+
+	t.Run("stream-json", func(t *testing.T) {
+		config := NewFilterConfig()
+		config.UseColor = false
+		config.Format = "stream-json"
+		config.Anonymizer = newAnonymizer(true)
+
+		var output bytes.Buffer
+		if err := processInput(strings.NewReader(input), &output, config); err != nil {
+			t.Fatalf("processInput() error = %v", err)
+		}
+		got := output.String()
+		for _, leak := range []string{"pankona", "sess-1", "built by"} {
+			if strings.Contains(got, leak) {
+				t.Errorf("output leaks %q:\n%s", leak, got)
+			}
+		}
+
+		// フィルタせずにすべての行を出力し、どの行も JSON として読める
+		lines := 0
+		scanner := bufio.NewScanner(strings.NewReader(got))
+		for scanner.Scan() {
+			lines++
+			if !json.Valid(scanner.Bytes()) {
+				t.Errorf("invalid JSON line: %s", scanner.Text())
+			}
+		}
+		if lines != 4 {
+			t.Errorf("got %d lines, want 4:\n%s", lines, got)
+		}
+
+		// 匿名化した出力を ccfilter で再び読める
+		reread := NewFilterConfig()
+		reread.UseColor = false
+		reread.AbsolutePaths = true
+		var text bytes.Buffer
+		if err := processInput(strings.NewReader(got), &text, reread); err != nil {
+			t.Fatalf("processInput() error = %v", err)
+		}
+		if !strings.Contains(text.String(), "/home/user1/src/ccfilter/hello.go") {
+			t.Errorf("re-read output has no anonymized path:\n%s", text.String())
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		config := NewFilterConfig()
+		config.UseColor = false
+		config.ShowSystem = true
+		config.AbsolutePaths = true
+		config.Anonymizer = newAnonymizer(false)
+
+		var output bytes.Buffer
+		if err := processInput(strings.NewReader(input), &output, config); err != nil {
+			t.Fatalf("processInput() error = %v", err)
+		}
+		got := output.String()
+		if strings.Contains(got, "pankona") {
+			t.Errorf("output leaks the username:\n%s", got)
+		}
+		if !strings.Contains(got, "by /home/user1/sdk/go") {
+			t.Errorf("output has no pseudonym:\n%s", got)
+		}
+	})
+}
+
+func TestProcessInputs_AnonymizeLabels(t *testing.T) {
+	session := func(id string) string {
+		return strings.Join([]string{
+			`{"type":"system","subtype":"init","cwd":"/work","session_id":"` + id + `","tools":[],"model":"claude"}`,
+			`{"type":"assistant","message":{"id":"m1","content":[{"type":"text","text":"hello"}]},"session_id":"` + id + `"}`,
+		}, "\n") + "\n"
+	}
+	config := NewFilterConfig()
+	config.UseColor = false
+	config.Anonymizer = newAnonymizer(false)
+
+	var output bytes.Buffer
+	sources := []InputSource{
+		{Reader: strings.NewReader(session("8f2c1e6a-aaaa-4bbb-8ccc-123456789abc"))},
+		{Reader: strings.NewReader(session("8f2c1e6a-dddd-4eee-8fff-123456789abc"))},
+	}
+	if err := processInputs(sources, &output, config); err != nil {
+		t.Fatalf("processInputs() error = %v", err)
+	}
+	got := output.String()
+	if strings.Contains(got, "8f2c1e6a") {
+		t.Errorf("labels leak the session_id:\n%s", got)
+	}
+	// 仮名の session_id から作るラベルもセッションごとに異なる
+	labels := make(map[string]bool)
+	for _, m := range regexp.MustCompile(`(?m)^\[([^\]]+)\] hello`).FindAllStringSubmatch(got, -1) {
+		labels[m[1]] = true
+	}
+	if len(labels) != 2 {
+		t.Errorf("labels = %v, want 2 distinct labels:\n%s", labels, got)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// castHeader は asciinema v2 形式のヘッダー行
+type castHeader struct {
+	Version       int               `json:"version"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	Timestamp     int64             `json:"timestamp,omitempty"`
+	IdleTimeLimit float64           `json:"idle_time_limit,omitempty"`
+	Env           map[string]string `json:"env"`
+}
+
+// castWriter は出力をそのまま asciinema v2 の .cast として記録する io.Writer
+// 各出力の時刻は、その出力のもとになった行の到着時刻 (at) を使う
+type castWriter struct {
+	w      io.Writer
+	width  int
+	height int
+	idle   time.Duration // 出力の間隔の上限 (0 の場合は上限なし)
+
+	at      time.Time // 現在処理中の行の到着時刻
+	last    time.Time
+	elapsed time.Duration
+	started bool
+}
+
+// newCastWriter は w に .cast を書き出す castWriter を作成
+func newCastWriter(w io.Writer, width, height int, idle time.Duration) *castWriter {
+	return &castWriter{w: w, width: width, height: height, idle: idle}
+}
+
+// Write は p を出力イベントとして記録
+func (c *castWriter) Write(p []byte) (int, error) {
+	at := c.at
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	if !c.started {
+		if err := c.writeHeader(at); err != nil {
+			return 0, err
+		}
+		c.started = true
+		c.last = at
+	}
+
+	// 長い待ち時間は idle の上限に切り詰める
+	if delta := at.Sub(c.last); delta > 0 {
+		if c.idle > 0 && delta > c.idle {
+			delta = c.idle
+		}
+		c.elapsed += delta
+		c.last = at
+	}
+
+	// 端末への出力として再生されるため改行は CRLF にする
+	data := strings.ReplaceAll(string(p), "\n", "\r\n")
+	event, err := json.Marshal([]interface{}{json.Number(fmt.Sprintf("%.6f", c.elapsed.Seconds())), "o", data})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := fmt.Fprintf(c.w, "%s\n", event); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeHeader は最初の出力の時刻を開始時刻としてヘッダーを書き出す
+func (c *castWriter) writeHeader(start time.Time) error {
+	header, err := json.Marshal(castHeader{
+		Version:       2,
+		Width:         c.width,
+		Height:        c.height,
+		Timestamp:     start.Unix(),
+		IdleTimeLimit: c.idle.Seconds(),
+		Env:           map[string]string{"TERM": "xterm-256color", "SHELL": "/bin/sh"},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.w, "%s\n", header)
+	return err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCastWriter(t *testing.T) {
+	var buf bytes.Buffer
+	c := newCastWriter(&buf, 100, 30, 2*time.Second)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	writes := []struct {
+		offset time.Duration
+		text   string
+	}{
+		{0, "first\n"},
+		{1500 * time.Millisecond, "second\n"},
+		{1 * time.Minute, "after a long wait\n"},
+	}
+	for _, w := range writes {
+		c.at = start.Add(w.offset)
+		if _, err := c.Write([]byte(w.text)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []string{
+		`{"version":2,"width":100,"height":30,"timestamp":1735689600,"idle_time_limit":2,"env":{"SHELL":"/bin/sh","TERM":"xterm-256color"}}`,
+		`[0.000000,"o","first\r\n"]`,
+		`[1.500000,"o","second\r\n"]`,
+		`[3.500000,"o","after a long wait\r\n"]`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("cast =\n%s\nwant:\n%s", buf.String(), strings.Join(want, "\n"))
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %s, want %s", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestProcessInput_CastFromRecording(t *testing.T) {
+	input := strings.Join([]string{
+		`{"ts":"2025-01-01T00:00:00Z","line":{"type":"assistant","message":{"content":[{"type":"text","text":"Looking"}]}}}`,
+		`{"ts":"2025-01-01T00:00:03Z","line":{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"a.go"}}]}}}`,
+		`{"ts":"2025-01-01T00:00:04.25Z","line":{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}}}`,
+	}, "\n")
+
+	path := filepath.Join(t.TempDir(), "run.cast")
+	config := NewFilterConfig()
+	config.CastPath = path
+
+	var output bytes.Buffer
+	if err := processInput(strings.NewReader(input), &output, config); err != nil {
+		t.Fatalf("processInput() error = %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("cast was not written: %v", err)
+	}
+	defer f.Close()
+
+	var printed strings.Builder
+	var times []float64
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // ヘッダー
+	for scanner.Scan() {
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", scanner.Text(), err)
+		}
+		times = append(times, event[0].(float64))
+		printed.WriteString(event[2].(string))
+	}
+
+	// 表示した内容がそのまま (色付きで) 記録される
+	if got, want := printed.String(), strings.ReplaceAll(output.String(), "\n", "\r\n"); got != want {
+		t.Errorf("cast output = %q, want %q", got, want)
+	}
+	if want := []float64{0, 3, 4.25}; len(times) != len(want) || times[0] != want[0] || times[1] != want[1] || times[2] != want[2] {
+		t.Errorf("event times = %v, want %v", times, want)
+	}
+}
@@ -11,26 +11,20 @@ const (
 	ColorGray   = "\x1b[90m"
 )
 
-// colorize はテキストを指定色で装飾
-func colorize(text, color string, enabled bool) string {
-	if !enabled {
+// colorize はテキストを役割 (または16色の名前) に対応するテーマのスタイルで装飾
+// colors が nil (カラー出力が無効) の場合や、役割にスタイルがない場合はそのまま返す
+func colorize(text, role string, colors *palette) string {
+	if colors == nil {
 		return text
 	}
 
-	switch color {
-	case "green":
-		return ColorGreen + text + ColorReset
-	case "yellow":
-		return ColorYellow + text + ColorReset
-	case "blue":
-		return ColorBlue + text + ColorReset
-	case "red":
-		return ColorRed + text + ColorReset
-	case "cyan":
-		return ColorCyan + text + ColorReset
-	case "gray":
-		return ColorGray + text + ColorReset
-	default:
+	style, ok := colors.theme.lookup(role)
+	if !ok {
 		return text
 	}
+	open := style.open(colors.depth)
+	if open == "" {
+		return text
+	}
+	return open + text + ColorReset
 }
@@ -2,6 +2,9 @@ package main
 
 import "testing"
 
+// defaultColors は組み込みのテーマ (dark) の16色のカラー出力
+var defaultColors = (&FilterConfig{UseColor: true}).colors()
+
 func TestColorize(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -77,7 +80,11 @@ func TestColorize(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := colorize(tt.text, tt.color, tt.enabled)
+			var colors *palette
+			if tt.enabled {
+				colors = defaultColors
+			}
+			got := colorize(tt.text, tt.color, colors)
 			if got != tt.want {
 				t.Errorf("colorize() = %q, want %q", got, tt.want)
 			}
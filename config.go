@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Config は設定ファイル (JSON) の内容
+type Config struct {
+	Theme          string                     `json:"theme"`           // 使用するテーマの名前
+	Themes         map[string]ThemeConfig     `json:"themes"`          // ユーザー定義のテーマ
+	MaxLines       *int                       `json:"max_lines"`       // ツール結果の先頭から表示する行数 (0 の場合は無制限)
+	TailLines      *int                       `json:"tail_lines"`      // ツール結果の末尾から表示する行数
+	ToolLines      map[string]ToolLinesConfig `json:"tool_lines"`      // ツールごとの表示する行数
+	Sanitize       string                     `json:"sanitize"`        // ツールの入出力の制御文字の扱い (escape, strip, off)
+	Redact         *bool                      `json:"redact"`          // 秘密情報を伏せる (省略時は true)
+	RedactPatterns map[string]string          `json:"redact_patterns"` // 追加で伏せる正規表現 (名前 → パターン)
+	AbsolutePaths  bool                       `json:"absolute_paths"`  // パスを相対パスや ~ にせずに表示
+}
+
+// ToolLinesConfig はツールごとの表示する行数
+type ToolLinesConfig struct {
+	MaxLines  *int `json:"max_lines"`  // 省略した場合は全体の指定に従う
+	TailLines int  `json:"tail_lines"` // 省略した場合は末尾を表示しない
+}
+
+// ThemeConfig は設定ファイルで定義するテーマ
+type ThemeConfig struct {
+	Base   string            `json:"base"`   // 元にする組み込みのテーマ (省略時は dark)
+	Styles map[string]string `json:"styles"` // 役割ごとのスタイル ("bold #ff8800 on 236" など)
+}
+
+// defaultConfigPath は設定ファイルの既定の場所を返す ($XDG_CONFIG_HOME/ccfilter/config.json)
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ccfilter", "config.json")
+}
+
+// loadConfig は設定ファイルを読み込む
+// path を明示しなかった場合は、既定の場所にファイルがなくてもエラーにしない
+func loadConfig(path string) (*Config, error) {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigPath()
+		if path == "" {
+			return &Config{}, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// resolveTheme は名前からテーマを作成 (ユーザー定義のテーマを組み込みのテーマより優先する)
+func (c *Config) resolveTheme(name string) (*Theme, error) {
+	if name == "" {
+		name = c.Theme
+	}
+	if name == "" {
+		name = defaultTheme
+	}
+
+	if tc, ok := c.Themes[name]; ok {
+		baseName := tc.Base
+		if baseName == "" {
+			baseName = defaultTheme
+		}
+		base, ok := builtinThemes[baseName]
+		if !ok {
+			return nil, fmt.Errorf("theme %q: unknown base theme %q (must be one of %s)", name, baseName, strings.Join(themeNames(), ", "))
+		}
+		return newTheme(name, base, tc.Styles)
+	}
+	if theme, ok := builtinThemes[name]; ok {
+		return theme, nil
+	}
+	return nil, fmt.Errorf("unknown theme: %s (must be one of %s or a theme defined in the config file)", name, strings.Join(themeNames(), ", "))
+}
+
+// applyLimits は設定ファイルの表示する行数を config に反映
+func (c *Config) applyLimits(config *FilterConfig) error {
+	if c.MaxLines != nil {
+		n, err := maxLinesValue(*c.MaxLines)
+		if err != nil {
+			return fmt.Errorf("invalid config: max_lines: %w", err)
+		}
+		config.MaxLines = n
+	}
+	if c.TailLines != nil {
+		if *c.TailLines < 0 {
+			return fmt.Errorf("invalid config: tail_lines: must not be negative")
+		}
+		config.TailLines = *c.TailLines
+	}
+	for tool, tc := range c.ToolLines {
+		limit := lineLimit{Tail: tc.TailLines}
+		if tc.MaxLines != nil {
+			n, err := maxLinesValue(*tc.MaxLines)
+			if err != nil {
+				return fmt.Errorf("invalid config: tool_lines: %s: %w", tool, err)
+			}
+			limit.Head = n
+		}
+		if limit.Tail < 0 {
+			return fmt.Errorf("invalid config: tool_lines: %s: tail_lines must not be negative", tool)
+		}
+		if config.ToolLines == nil {
+			config.ToolLines = make(map[string]lineLimit)
+		}
+		config.ToolLines[tool] = limit
+	}
+	return nil
+}
+
+// maxLinesValue は指定された行数を MaxLines の値に変換 (0 は無制限)
+func maxLinesValue(n int) (int, error) {
+	if n < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	if n == 0 {
+		return -1, nil
+	}
+	return n, nil
+}
+
+// parseToolLines は --tool-lines の "TOOL=HEAD[:TAIL]" を解析
+func parseToolLines(spec string) (string, lineLimit, error) {
+	tool, value, ok := strings.Cut(spec, "=")
+	if !ok || tool == "" {
+		return "", lineLimit{}, fmt.Errorf("invalid --tool-lines: %s (must be TOOL=HEAD[:TAIL])", spec)
+	}
+	head, tail, _ := strings.Cut(value, ":")
+
+	var limit lineLimit
+	n, err := strconv.Atoi(head)
+	if err == nil {
+		limit.Head, err = maxLinesValue(n)
+	}
+	if err == nil && tail != "" {
+		limit.Tail, err = strconv.Atoi(tail)
+		if err == nil && limit.Tail < 0 {
+			err = fmt.Errorf("must not be negative")
+		}
+	}
+	if err != nil {
+		return "", lineLimit{}, fmt.Errorf("invalid --tool-lines: %s: %w", spec, err)
+	}
+	return tool, limit, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	writeFile(t, path, `{"theme": "mine", "themes": {"mine": {"base": "light", "styles": {"error": "bold white on 160"}}}}`)
+
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	theme, err := config.resolveTheme("")
+	if err != nil {
+		t.Fatalf("resolveTheme() error = %v", err)
+	}
+	if theme.Name != "mine" {
+		t.Errorf("Name = %q, want mine", theme.Name)
+	}
+	if got, want := theme.Styles[roleError].open(depth256), StyleBold+"\x1b[37m\x1b[48;5;160m"; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+	if theme.Styles[roleTool] != builtinThemes["light"].Styles[roleTool] {
+		t.Error("tool should come from the light theme")
+	}
+
+	// フラグで指定したテーマが優先
+	theme, err = config.resolveTheme("solarized")
+	if err != nil || theme != builtinThemes["solarized"] {
+		t.Errorf("resolveTheme(solarized) = %v, %v", theme, err)
+	}
+}
+
+func TestLoadConfig_Missing(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	// 既定の場所にない場合はデフォルト
+	config, err := loadConfig("")
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	theme, err := config.resolveTheme("")
+	if err != nil || theme != builtinThemes[defaultTheme] {
+		t.Errorf("resolveTheme() = %v, %v", theme, err)
+	}
+
+	// 明示したファイルがない場合はエラー
+	if _, err := loadConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error for missing explicit config")
+	}
+}
+
+func TestResolveTheme_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		theme   string
+		wantErr string
+	}{
+		{name: "unknown theme", theme: "neon", wantErr: "unknown theme: neon"},
+		{name: "unknown base", config: Config{Themes: map[string]ThemeConfig{"mine": {Base: "neon"}}}, theme: "mine", wantErr: `unknown base theme "neon"`},
+		{name: "bad style", config: Config{Themes: map[string]ThemeConfig{"mine": {Styles: map[string]string{"tool": "bold purple"}}}}, theme: "mine", wantErr: `invalid color "purple"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.config.resolveTheme(tt.theme)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("resolveTheme() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeFile(t, path, `{"max_lines": 10, "tail_lines": 3, "tool_lines": {"Bash": {"max_lines": 20, "tail_lines": 10}, "Read": {"max_lines": 0}, "Grep": {"tail_lines": 2}}}`)
+
+	settings, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := NewFilterConfig()
+	if err := settings.applyLimits(config); err != nil {
+		t.Fatalf("applyLimits() error = %v", err)
+	}
+	if config.MaxLines != 10 || config.TailLines != 3 {
+		t.Errorf("MaxLines, TailLines = %d, %d, want 10, 3", config.MaxLines, config.TailLines)
+	}
+	want := map[string]lineLimit{"Bash": {Head: 20, Tail: 10}, "Read": {Head: -1}, "Grep": {Tail: 2}}
+	for tool, limit := range want {
+		if config.ToolLines[tool] != limit {
+			t.Errorf("ToolLines[%s] = %+v, want %+v", tool, config.ToolLines[tool], limit)
+		}
+	}
+
+	writeFile(t, path, `{"tool_lines": {"Bash": {"max_lines": -1}}}`)
+	if settings, err = loadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := settings.applyLimits(NewFilterConfig()); err == nil {
+		t.Error("expected error for negative max_lines")
+	}
+}
+
+func TestParseToolLines(t *testing.T) {
+	tests := []struct {
+		spec      string
+		wantTool  string
+		wantLimit lineLimit
+		wantErr   bool
+	}{
+		{spec: "Bash=20:10", wantTool: "Bash", wantLimit: lineLimit{Head: 20, Tail: 10}},
+		{spec: "Read=3", wantTool: "Read", wantLimit: lineLimit{Head: 3}},
+		{spec: "Bash=0", wantTool: "Bash", wantLimit: lineLimit{Head: -1}},
+		{spec: "Bash", wantErr: true},
+		{spec: "=3", wantErr: true},
+		{spec: "Bash=many", wantErr: true},
+		{spec: "Bash=3:-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tool, limit, err := parseToolLines(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseToolLines(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if tool != tt.wantTool || limit != tt.wantLimit {
+			t.Errorf("parseToolLines(%q) = %s, %+v, want %s, %+v", tt.spec, tool, limit, tt.wantTool, tt.wantLimit)
+		}
+	}
+}
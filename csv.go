@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// toolCallsCSVHeader は --format=csv の列
+var toolCallsCSVHeader = []string{"session_id", "sequence", "tool", "parameter", "is_error", "result_bytes"}
+
+// metricsCSVHeader は --metrics-csv の列
+var metricsCSVHeader = []string{
+	"session_id", "model", "duration_ms", "duration_api_ms", "cost_usd", "turns",
+	"input_tokens", "output_tokens", "cache_read_input_tokens", "cache_creation_input_tokens",
+}
+
+// writeToolCallsCSV はツール呼び出しを1行ずつ CSV (RFC 4180) で書き出す
+func writeToolCallsCSV(output io.Writer, streams []*stream) error {
+	w := csv.NewWriter(output)
+	if err := w.Write(toolCallsCSVHeader); err != nil {
+		return err
+	}
+
+	for _, s := range streams {
+		t := s.config.Tracker
+		sessionID := t.SessionID()
+		sequence := 0
+		for _, step := range t.Steps {
+			if step.Kind != "tool" {
+				continue
+			}
+			sequence++
+			_, param, _ := mainParam(step.Name, step.Input)
+			if err := w.Write([]string{
+				sessionID,
+				strconv.Itoa(sequence),
+				step.Name,
+				param,
+				strconv.FormatBool(step.IsError),
+				strconv.Itoa(len(step.Result)),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// writeMetricsCSV はセッションごとのメトリクスを1行ずつ CSV で書き出す
+func writeMetricsCSV(path string, streams []*stream) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics file: %w", err)
+	}
+	defer f.Close()
+
+	return encodeMetricsCSV(f, streams)
+}
+
+// encodeMetricsCSV はセッションごとのメトリクスを w に書き出す
+func encodeMetricsCSV(output io.Writer, streams []*stream) error {
+	w := csv.NewWriter(output)
+	if err := w.Write(metricsCSVHeader); err != nil {
+		return err
+	}
+
+	for _, s := range streams {
+		t := s.config.Tracker
+		stats := computeStats(t)
+
+		var model string
+		if t.Init != nil {
+			model = t.Init.Model
+		} else if names := sortedKeys(stats.Models); len(names) > 0 {
+			model = names[0]
+		}
+
+		// ターン数は result の num_turns を優先
+		durationAPIMs, turns := 0, stats.Turns
+		if t.Result != nil {
+			durationAPIMs, turns = t.Result.DurationAPIMs, t.Result.NumTurns
+		}
+
+		if err := w.Write([]string{
+			stats.SessionID,
+			model,
+			strconv.Itoa(stats.DurationMs),
+			strconv.Itoa(durationAPIMs),
+			formatFloat(stats.CostUsd),
+			strconv.Itoa(turns),
+			strconv.Itoa(stats.Tokens.InputTokens),
+			strconv.Itoa(stats.Tokens.OutputTokens),
+			strconv.Itoa(stats.Tokens.CacheReadInputTokens),
+			strconv.Itoa(stats.Tokens.CacheCreationInputTokens),
+		}); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestProcessInput_CSV(t *testing.T) {
+	input := strings.Join([]string{
+		`{"type":"system","subtype":"init","session_id":"s1","model":"sonnet"}`,
+		`{"type":"assistant","message":{"id":"m1","content":[{"type":"text","text":"hi"},{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"echo \"a, b\""}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"a, b"}]}}`,
+		`{"type":"assistant","message":{"id":"m2","content":[{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"main.go"}},{"type":"tool_use","id":"t3","name":"TodoWrite","input":{"todos":[]}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t2","content":"not found","is_error":true}]}}`,
+		`{"type":"result","subtype":"success","result":"done","session_id":"s1","num_turns":2}`,
+	}, "\n")
+
+	config := NewFilterConfig()
+	config.Format = "csv"
+	config.ShowStats = true
+
+	var output bytes.Buffer
+	if err := processInput(strings.NewReader(input), &output, config); err != nil {
+		t.Fatalf("processInput() error = %v", err)
+	}
+
+	want := "session_id,sequence,tool,parameter,is_error,result_bytes\n" +
+		"s1,1,Bash,\"echo \"\"a, b\"\"\",false,4\n" +
+		"s1,2,Read,main.go,true,9\n" +
+		"s1,3,TodoWrite,,false,0\n"
+	if got := output.String(); got != want {
+		t.Errorf("CSV output =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEncodeMetricsCSV(t *testing.T) {
+	tracker := trackFile(t, "testdata/permission_denied.json")
+	empty := newTracker()
+
+	var output bytes.Buffer
+	streams := []*stream{
+		{config: &FilterConfig{Tracker: tracker}},
+		{config: &FilterConfig{Tracker: empty}},
+	}
+	if err := encodeMetricsCSV(&output, streams); err != nil {
+		t.Fatalf("encodeMetricsCSV() error = %v", err)
+	}
+
+	want := "session_id,model,duration_ms,duration_api_ms,cost_usd,turns,input_tokens,output_tokens,cache_read_input_tokens,cache_creation_input_tokens\n" +
+		"ef076ce9-9d77-43cd-895c-c7686b45a9a0,claude-sonnet-4-5-20250929,7708,13293,0.017985349999999997,4,825,622,29617,723\n" +
+		",,0,0,0,0,0,0,0,0\n"
+	if got := output.String(); got != want {
+		t.Errorf("metrics CSV =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteMetricsCSV_File(t *testing.T) {
+	input, err := os.ReadFile("testdata/permission_denied.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := t.TempDir() + "/metrics.csv"
+	config := NewFilterConfig()
+	config.MetricsCSVPath = path
+
+	var output bytes.Buffer
+	if err := processInput(bytes.NewReader(input), &output, config); err != nil {
+		t.Fatalf("processInput() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("metrics CSV was not written: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"); len(lines) != 2 {
+		t.Errorf("metrics CSV should have a header and one row, got %d lines", len(lines))
+	}
+}
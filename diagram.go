@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// diagramLabelMax は図に載せるパラメータや結果の最大文字数
+const diagramLabelMax = 60
+
+// flowEvent はシーケンス図の1つの矢印 (ツール呼び出しまたは結果)
+type flowEvent struct {
+	at     time.Time
+	step   *Step
+	result bool
+}
+
+// writeMermaid は各セッションのツールの流れを Mermaid のシーケンス図として書き出す
+// 複数の入力がある場合はセッションごとに図を分ける
+func writeMermaid(w io.Writer, streams []*stream) error {
+	var diagrams []string
+	for _, s := range streams {
+		diagrams = append(diagrams, mermaidSequence(s.config.Tracker))
+	}
+	_, err := io.WriteString(w, strings.Join(diagrams, "\n"))
+	return err
+}
+
+// mermaidSequence は User, Claude, サブエージェント, ツールを参加者とするシーケンス図を作成
+func mermaidSequence(t *Tracker) string {
+	var participants, arrows []string
+	declared := make(map[string]bool)
+	declare := func(id, label string) string {
+		if !declared[id] {
+			declared[id] = true
+			participants = append(participants, fmt.Sprintf("    participant %s as %s", id, mermaidText(label)))
+		}
+		return id
+	}
+	declare("User", "User")
+	declare("Claude", "Claude")
+
+	// ツールを呼び出した側 (メインエージェントまたは Task が起動したサブエージェント)
+	caller := func(step *Step) string {
+		if step.ParentID == "" {
+			return "Claude"
+		}
+		return declare("Agent_"+mermaidID(step.ParentID), subagentName(t, step.ParentID))
+	}
+
+	arrows = append(arrows, "    User->>Claude: prompt")
+	for _, e := range flowEvents(t) {
+		from := caller(e.step)
+		var to string
+		if e.step.Name == "Task" {
+			to = declare("Agent_"+mermaidID(e.step.ID), subagentName(t, e.step.ID))
+		} else {
+			to = declare("Tool_"+mermaidID(e.step.Name), e.step.Name)
+		}
+
+		switch {
+		case !e.result:
+			_, param, _ := mainParam(e.step.Name, e.step.Input)
+			if e.step.Name == "Task" {
+				param = taskDescription(e.step)
+			}
+			arrows = append(arrows, fmt.Sprintf("    %s->>%s: %s", from, to, mermaidText(joinNonEmpty(" ", e.step.Name, shorten(param)))))
+		case e.step.IsError:
+			arrows = append(arrows, fmt.Sprintf("    %s--x%s: %s", to, from, mermaidText("error: "+shorten(e.step.Result))))
+		default:
+			arrows = append(arrows, fmt.Sprintf("    %s-->>%s: %s", to, from, mermaidText(shorten(e.step.Result))))
+		}
+	}
+	if r := t.Result; r != nil {
+		label := "result"
+		if r.IsError {
+			label = "error: " + r.Subtype
+		}
+		arrows = append(arrows, fmt.Sprintf("    Claude-->>User: %s", mermaidText(label)))
+	}
+
+	return "sequenceDiagram\n" + strings.Join(participants, "\n") + "\n" + strings.Join(arrows, "\n") + "\n"
+}
+
+// flowEvents はツール呼び出しと結果を発生順に並べる (結果の届いていない呼び出しは呼び出しのみ)
+func flowEvents(t *Tracker) []flowEvent {
+	var events []flowEvent
+	for _, step := range t.Steps {
+		if step.Kind == "tool" {
+			events = append(events, flowEvent{at: step.Start, step: step})
+		}
+	}
+	for _, step := range t.Steps {
+		if step.Kind == "tool" && step.Done {
+			events = append(events, flowEvent{at: step.End, step: step, result: true})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].at.Before(events[j].at)
+	})
+	return events
+}
+
+// writeDot はサブエージェントとツール呼び出しのツリーを Graphviz の DOT として書き出す
+func writeDot(w io.Writer, streams []*stream) error {
+	var output strings.Builder
+	output.WriteString("digraph ccfilter {\n")
+	output.WriteString("  rankdir=LR;\n")
+	output.WriteString("  node [shape=box, fontname=\"Helvetica\"];\n")
+
+	for i, s := range streams {
+		t := s.config.Tracker
+		root := fmt.Sprintf("s%d", i+1)
+		label := "Claude session"
+		if id := t.SessionID(); id != "" {
+			label += "\n" + sessionLabel("", id, "")
+		}
+		attrs := ""
+		if t.Result != nil && t.Result.IsError {
+			attrs = ", color=red"
+		}
+		output.WriteString(fmt.Sprintf("  %s [label=%s, shape=ellipse%s];\n", root, dotQuote(label), attrs))
+
+		// Task の呼び出しはサブエージェントとして、その中のツール呼び出しの親になる
+		nodes := make(map[string]string)
+		n := 0
+		for _, step := range t.Steps {
+			if step.Kind != "tool" {
+				continue
+			}
+			n++
+			node := fmt.Sprintf("%s_%d", root, n)
+			nodes[step.ID] = node
+
+			parent := root
+			if p, ok := nodes[step.ParentID]; ok {
+				parent = p
+			}
+
+			_, detail, _ := mainParam(step.Name, step.Input)
+			attrs := ""
+			if step.Name == "Task" {
+				detail = taskDescription(step)
+				attrs = ", shape=box3d"
+			}
+			if step.IsError {
+				attrs += ", color=red, fontcolor=red"
+			}
+			output.WriteString(fmt.Sprintf("  %s [label=%s%s];\n", node, dotQuote(joinNonEmpty("\n", step.Name, shorten(detail))), attrs))
+			output.WriteString(fmt.Sprintf("  %s -> %s;\n", parent, node))
+		}
+	}
+
+	output.WriteString("}\n")
+	_, err := io.WriteString(w, output.String())
+	return err
+}
+
+// subagentName は Task の tool_use_id からサブエージェントの表示名を作る
+func subagentName(t *Tracker, taskID string) string {
+	if call := t.Call(taskID); call != nil {
+		if desc := taskDescription(call); desc != "" {
+			return "Subagent: " + desc
+		}
+	}
+	return "Subagent"
+}
+
+// taskDescription は Task の入力の description を返す
+func taskDescription(step *Step) string {
+	var input struct {
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(step.Input, &input); err != nil {
+		return ""
+	}
+	return input.Description
+}
+
+// shorten は図に載せるため1行目を diagramLabelMax 文字に切り詰める
+func shorten(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	if r := []rune(line); len(r) > diagramLabelMax {
+		return string(r[:diagramLabelMax]) + "..."
+	}
+	return line
+}
+
+// mermaidIDPattern は Mermaid の参加者 ID に使えない文字
+var mermaidIDPattern = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// mermaidID は参加者 ID に使える文字列にする
+func mermaidID(s string) string {
+	return mermaidIDPattern.ReplaceAllString(s, "_")
+}
+
+// mermaidEscaper はメッセージ中で構文として解釈される文字をエンティティに置き換える
+var mermaidEscaper = strings.NewReplacer("#", "#35;", ";", "#59;", "\n", " ")
+
+// mermaidText は参加者名やメッセージに使える文字列にする
+func mermaidText(s string) string {
+	return mermaidEscaper.Replace(s)
+}
+
+// dotQuote は DOT の文字列リテラルにする
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
@@ -4,16 +4,51 @@ import "encoding/json"
 
 // FilterConfig はフィルタリングの設定を保持する構造体
 type FilterConfig struct {
-	ShowSystem    bool
-	ShowAssistant bool
-	ShowTools     bool
-	ShowResult    bool
-	InfoLevel     string // "minimal", "standard", "verbose"
-	ShowCost      bool
-	ShowUsage     bool
-	ShowTiming    bool
-	Format        string // "text", "json", "compact"
-	UseColor      bool
+	ShowSystem      bool
+	ShowAssistant   bool
+	ShowTools       bool
+	ShowResult      bool
+	InfoLevel       string // "minimal", "standard", "verbose"
+	ShowCost        bool
+	ShowUsage       bool
+	ShowTiming      bool
+	Format          string // "text", "json", "compact", "markdown", "html", "csv", "mermaid", "dot", "stream-json"
+	UseColor        bool
+	ColorMode       string               // "auto", "always", "never" (auto の場合は出力先に応じて UseColor を決める)
+	Theme           *Theme               // カラー出力に使うテーマ (nil の場合は組み込みの dark)
+	ColorDepth      colorDepth           // 出力先の端末が表示できる色数
+	Width           int                  // 折り返す表示幅 (0 の場合は折り返さない、負の場合は出力先の端末から判定)
+	MaxLineWidth    int                  // ツール結果の1行の最大の表示幅 (0 の場合は無制限、負の場合は情報レベルに応じて決める)
+	MaxLines        int                  // ツール結果の先頭から表示する行数 (0 の場合は情報レベルに応じて決める、負の場合は無制限)
+	TailLines       int                  // ツール結果の末尾から表示する行数
+	ToolLines       map[string]lineLimit // ツールごとの表示する行数
+	Sanitize        string               // ツールの入出力の制御文字の扱い ("escape", "strip", "off"、空の場合は escape)
+	Redactor        *redactor            // 秘密情報を伏せる (nil の場合は伏せない)
+	Anonymizer      *anonymizer          // ホームディレクトリ・ユーザー名・ID を仮名にする (nil の場合は匿名化しない)
+	AbsolutePaths   bool                 // ツールのパラメータと結果のパスを相対パスや ~ にせずに表示
+	HomeDir         string               // ~ で表示するホームディレクトリ (空の場合は ~ にしない)
+	Inputs          []InputSource        // 入力元 (空の場合は標準入力)
+	Command         string               // サブコマンド ("", "replay", "report" または "anonymize")
+	OutputPath      string               // 出力先のファイル (空の場合は標準出力)
+	RecordPath      string               // 到着時刻付きで入力を記録するファイル
+	ReplaySpeed     float64              // replay の再生速度 (0 の場合は待機なし)
+	ShowLatency     bool                 // ツール結果にレイテンシを表示
+	ShowTimeline    bool                 // 終了時にタイムラインを表示
+	ShowStats       bool                 // 終了時に統計情報を表示
+	StatsJSONPath   string               // 統計情報をJSONで書き出すファイル
+	MetricsCSVPath  string               // セッションごとのメトリクスをCSVで書き出すファイル
+	JUnitPath       string               // セッションを JUnit XML で書き出すファイル
+	GitHubActions   bool                 // GitHub Actions のワークフローコマンドを出力
+	OTLPFile        string               // トレースを OTLP/JSON で書き出すファイル
+	OTLPEndpoint    string               // トレースを送信する OTLP/HTTP のエンドポイント
+	TraceEventsPath string               // Chrome Trace Event 形式で書き出すファイル
+	PromTextfile    string               // node_exporter の textfile collector 用に書き出すファイル
+	PromPushURL     string               // メトリクスを送信する Pushgateway の URL
+	CastPath        string               // 出力を asciinema v2 形式で記録するファイル
+	CastWidth       int                  // .cast の端末の幅
+	CastHeight      int                  // .cast の端末の高さ
+	CastIdleLimit   float64              // .cast の出力の間隔の上限 (秒、0 の場合は上限なし)
+	Tracker         *Tracker             // 入力ストリームごとの追跡状態 (nil の場合は追跡しない)
 }
 
 // NewFilterConfig はデフォルト設定でFilterConfigを作成
@@ -25,23 +60,39 @@ func NewFilterConfig() *FilterConfig {
 		InfoLevel:     "standard",
 		Format:        "text",
 		UseColor:      true,
+		ColorMode:     "auto",
+		Width:         -1,
+		MaxLineWidth:  -1,
+		Sanitize:      "escape",
+		CastWidth:     80,
+		CastHeight:    24,
 	}
 }
 
 // parseMessageType はJSON行からメッセージタイプを判定
 func parseMessageType(line string) (string, error) {
-	var msg Message
-	if err := json.Unmarshal([]byte(line), &msg); err != nil {
+	msg, err := parseMessage(line)
+	if err != nil {
 		return "", err
 	}
 	return msg.Type, nil
 }
 
+// parseMessage はJSON行から全メッセージ共通のフィールドを取り出す
+func parseMessage(line string) (Message, error) {
+	var msg Message
+	if err := json.Unmarshal([]byte(line), &msg); err != nil {
+		return Message{}, err
+	}
+	return msg, nil
+}
+
 // shouldDisplay はメッセージを表示すべきかどうかを判定
 func shouldDisplay(msgType string, config *FilterConfig) bool {
 	switch msgType {
 	case "system":
-		return config.ShowSystem
+		// markdown ではセッションのヘッダーとして常に使う
+		return config.ShowSystem || config.Format == "markdown"
 	case "assistant":
 		return config.ShowAssistant
 	case "user":
@@ -58,6 +109,9 @@ func shouldDisplayContent(content Content, config *FilterConfig) bool {
 	switch content.Type {
 	case "text":
 		return config.ShowAssistant
+	case "thinking":
+		// 長くなりやすいので verbose のときだけ表示する
+		return config.ShowAssistant && config.InfoLevel == "verbose"
 	case "tool_use":
 		return config.ShowTools
 	default:
@@ -102,6 +102,12 @@ func TestShouldDisplay(t *testing.T) {
 			config:  FilterConfig{ShowSystem: true},
 			want:    true,
 		},
+		{
+			name:    "system in markdown format",
+			msgType: "system",
+			config:  FilterConfig{ShowSystem: false, Format: "markdown"},
+			want:    true,
+		},
 		{
 			name:    "assistant with ShowAssistant=false",
 			msgType: "assistant",
@@ -3,21 +3,48 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
 )
 
 // formatMessage はメッセージを人間が読みやすい形式にフォーマット
 func formatMessage(msgType string, data []byte, config *FilterConfig) (string, error) {
+	if isReportFormat(config.Format) {
+		// 入力の終了後にまとめて出力する
+		return "", nil
+	}
+	if config.Format == "markdown" {
+		return formatMarkdownMessage(msgType, data, config)
+	}
+
+	var formatted string
+	var err error
 	switch msgType {
 	case "assistant":
-		return formatAssistantMessage(data, config)
+		formatted, err = formatAssistantMessage(data, config)
 	case "user":
-		return formatUserMessage(data, config)
+		formatted, err = formatUserMessage(data, config)
 	case "result":
-		return formatResultMessage(data, config)
-	default:
-		return "", nil
+		formatted, err = formatResultMessage(data, config)
+	}
+	if err != nil {
+		return "", err
+	}
+	return wrapText(formatted, config.Width), nil
+}
+
+// isTextFormat は端末向けのテキストで出力する形式かどうかを判定 (空の場合は text)
+func isTextFormat(format string) bool {
+	return format == "" || format == "text"
+}
+
+// isReportFormat は入力の終了後に Tracker の内容からまとめて出力する形式かどうかを判定
+func isReportFormat(format string) bool {
+	switch format {
+	case "html", "csv", "mermaid", "dot":
+		return true
 	}
+	return false
 }
 
 // formatAssistantMessage は AssistantMessage をフォーマット
@@ -35,8 +62,11 @@ func formatAssistantMessage(data []byte, config *FilterConfig) (string, error) {
 
 		switch content.Type {
 		case "text":
-			output.WriteString(content.Text)
+			// モデルの出力もツールの出力を引用することがあるので同様に扱う
+			output.WriteString(renderMarkdown(sanitizeText(content.Text, config), config.colors()))
 			output.WriteString("\n")
+		case "thinking":
+			output.WriteString(formatThinking(content.Thinking, config))
 		case "tool_use":
 			formatted := formatToolUse(content, config)
 			output.WriteString(formatted)
@@ -46,6 +76,20 @@ func formatAssistantMessage(data []byte, config *FilterConfig) (string, error) {
 	return output.String(), nil
 }
 
+// formatThinking は thinking ブロックを見出しと字下げした本文にする
+// (行ごとに色を閉じるので、複数入力のラベルを付けてもスタイルが崩れない)
+func formatThinking(text string, config *FilterConfig) string {
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	var output strings.Builder
+	output.WriteString(colorize("Thinking:", roleThinking, config.colors()) + "\n")
+	for _, line := range strings.Split(strings.TrimRight(sanitizeText(text, config), "\n"), "\n") {
+		output.WriteString("  " + colorize(line, roleThinking, config.colors()) + "\n")
+	}
+	return output.String()
+}
+
 // formatUserMessage は UserMessage をフォーマット
 func formatUserMessage(data []byte, config *FilterConfig) (string, error) {
 	var msg UserMessage
@@ -68,11 +112,11 @@ func formatUserMessage(data []byte, config *FilterConfig) (string, error) {
 func formatToolUse(content Content, config *FilterConfig) string {
 	var output strings.Builder
 
-	arrow := colorize("→", "cyan", config.UseColor)
+	arrow := colorize("→", roleArrow, config.colors())
 	output.WriteString(arrow)
 	output.WriteString(" ")
 
-	toolName := colorize(content.Name, "blue", config.UseColor)
+	toolName := colorize(sanitizeText(content.Name, config), toolRole(content.Name), config.colors())
 	output.WriteString(toolName)
 
 	// minimal モードではツール名のみ
@@ -81,88 +125,213 @@ func formatToolUse(content Content, config *FilterConfig) string {
 		return output.String()
 	}
 
-	// standard/verbose モードではパラメータも表示
+	// standard/verbose モードではパラメータも表示 (作業ディレクトリ以下のパスは相対パスにする)
 	if len(content.Input) > 0 {
-		params := extractMainParams(content.Name, content.Input)
-		if params != "" {
+		if key, value, ok := mainParam(content.Name, content.Input); ok {
 			output.WriteString(": ")
-			output.WriteString(params)
+			output.WriteString(fmt.Sprintf("%s=%q", key, relativizePaths(value, config)))
 		}
 	}
 
 	output.WriteString("\n")
+
+	// verbose モードでは Write の内容と Edit の差分も表示
+	if config.InfoLevel == "verbose" {
+		output.WriteString(formatEditDiff(content, config))
+	}
+	return output.String()
+}
+
+// toolRole はツール名の表示に使う役割を返す (サブエージェントを起動する Task は区別する)
+func toolRole(name string) string {
+	if name == "Task" {
+		return roleSubagent
+	}
+	return roleTool
+}
+
+// formatEditDiff は Write/Edit/MultiEdit の変更内容をファイルの言語でハイライトして表示
+func formatEditDiff(content Content, config *FilterConfig) string {
+	lines := editDiff(&Step{Name: content.Name, Input: content.Input})
+	if len(lines) == 0 {
+		return ""
+	}
+
+	_, path, _ := mainParam(content.Name, content.Input)
+	lang := languageFromPath(path)
+	highlighters := map[string]*highlighter{
+		"del": newHighlighter(lang, config.colors()),
+		"add": newHighlighter(lang, config.colors()),
+	}
+
+	var output strings.Builder
+	for _, line := range lines {
+		marker := colorize("+", roleDiffAdd, config.colors())
+		if line.Op == "del" {
+			marker = colorize("-", roleDiffDel, config.colors())
+		}
+		output.WriteString("  " + marker + " " + highlighters[line.Op].line(sanitizeText(line.Text, config)) + "\n")
+	}
 	return output.String()
 }
 
 // extractMainParams はツールの主要パラメータを抽出
 func extractMainParams(toolName string, input []byte) string {
+	key, value, ok := mainParam(toolName, input)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s=%q", key, value)
+}
+
+// mainParam はツールの主要パラメータの名前と値を返す
+func mainParam(toolName string, input []byte) (key, value string, ok bool) {
 	var params map[string]interface{}
 	if err := json.Unmarshal(input, &params); err != nil {
-		return ""
+		return "", "", false
 	}
 
 	// ツールごとに主要なパラメータを選択
 	switch toolName {
-	case "Glob":
-		if pattern, ok := params["pattern"].(string); ok {
-			return fmt.Sprintf("pattern=%q", pattern)
-		}
+	case "Glob", "Grep":
+		key = "pattern"
 	case "Bash":
-		if command, ok := params["command"].(string); ok {
-			return fmt.Sprintf("command=%q", command)
-		}
-	case "Read":
-		if path, ok := params["file_path"].(string); ok {
-			return fmt.Sprintf("file_path=%q", path)
-		}
-	case "Write", "Edit":
-		if path, ok := params["file_path"].(string); ok {
-			return fmt.Sprintf("file_path=%q", path)
-		}
-	case "Grep":
-		if pattern, ok := params["pattern"].(string); ok {
-			return fmt.Sprintf("pattern=%q", pattern)
-		}
+		key = "command"
+	case "Read", "Write", "Edit":
+		key = "file_path"
+	default:
+		return "", "", false
 	}
 
-	return ""
+	value, ok = params[key].(string)
+	return key, value, ok
 }
 
 // formatToolResult は tool_result をフォーマット
 func formatToolResult(result ToolResult, config *FilterConfig) string {
 	var output strings.Builder
 
-	arrow := colorize("←", "cyan", config.UseColor)
+	arrow := colorize("←", roleArrow, config.colors())
 	output.WriteString(arrow)
 	output.WriteString(" ")
 
+	if config.ShowLatency && config.Tracker != nil {
+		if latency, ok := config.Tracker.Latency(result.ToolUseID); ok {
+			output.WriteString(colorize(formatLatency(latency), roleMetrics, config.colors()))
+			output.WriteString(" ")
+		}
+	}
+
 	if result.IsError {
-		errorText := colorize("Error:", "red", config.UseColor)
+		errorText := colorize("Error:", roleError, config.colors())
 		output.WriteString(errorText)
 		output.WriteString(" ")
 	}
 
-	// minimal モードでは1行のみ
+	// 端末を操作する制御文字を無害化してから、1行が極端に長い結果 (minify された JSON など) を表示幅で切り詰める
+	result.Content = capLines(sanitizeText(relativizePaths(result.Content, config), config), maxLineWidth(config))
+
+	// minimal モードでは1行のみ (端末の幅に収める)
 	if config.InfoLevel == "minimal" {
 		firstLine := strings.Split(result.Content, "\n")[0]
+		if config.Width > 0 {
+			firstLine = truncateWidth(firstLine, config.Width-displayWidth(output.String()))
+		}
 		output.WriteString(firstLine)
 		output.WriteString("\n")
 		return output.String()
 	}
 
-	// standard モードでは適度に省略
-	maxLines := 5
-	if config.InfoLevel == "verbose" {
-		maxLines = -1 // 無制限
-	}
-
-	truncated := truncateOutput(result.Content, maxLines)
+	limit := resultLimit(config, resultTool(config, result.ToolUseID))
+	truncated := truncateLines(highlightResult(result, config), limit)
 	output.WriteString(truncated)
 	output.WriteString("\n")
 
 	return output.String()
 }
 
+// readLinePattern は Read の結果の行番号 ("     1→")
+var readLinePattern = regexp.MustCompile(`^(\s*\d+(?:→|\t))(.*)$`)
+
+// highlightResult は Read の結果を読んだファイルの言語でハイライト
+func highlightResult(result ToolResult, config *FilterConfig) string {
+	if !config.UseColor || result.IsError || config.Tracker == nil {
+		return result.Content
+	}
+	call := config.Tracker.Call(result.ToolUseID)
+	if call == nil || call.Name != "Read" {
+		return result.Content
+	}
+	_, path, _ := mainParam(call.Name, call.Input)
+	lang := languageFromPath(path)
+	if lang == "" {
+		return result.Content
+	}
+
+	colors := config.colors()
+	h := newHighlighter(lang, colors)
+	lines := strings.Split(result.Content, "\n")
+	for i, line := range lines {
+		if m := readLinePattern.FindStringSubmatch(line); m != nil {
+			lines[i] = colorize(m[1], roleMuted, colors) + h.line(m[2])
+		} else {
+			lines[i] = h.line(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// defaultMaxLines は standard モードでツール結果を表示する行数
+const defaultMaxLines = 5
+
+// lineLimit はツール結果を表示する行数 (先頭の Head 行と末尾の Tail 行)
+type lineLimit struct {
+	Head int // 先頭から表示する行数 (0 の場合は既定値、負の場合は無制限)
+	Tail int // 末尾から表示する行数
+}
+
+// resultLimit はツール結果を表示する行数を返す (ツールごとの指定 > 全体の指定 > 情報レベルの既定値)
+func resultLimit(config *FilterConfig, tool string) lineLimit {
+	limit := lineLimit{Head: config.MaxLines, Tail: config.TailLines}
+	if override, ok := config.ToolLines[tool]; ok {
+		if override.Head != 0 {
+			limit.Head = override.Head
+		}
+		limit.Tail = override.Tail
+	}
+	if limit.Head == 0 {
+		// standard モードでは適度に省略
+		limit.Head = defaultMaxLines
+		if config.InfoLevel == "verbose" {
+			limit.Head = -1 // 無制限
+		}
+	}
+	return limit
+}
+
+// resultTool はツール結果に対応するツール名を返す (追跡していない場合は空)
+func resultTool(config *FilterConfig, toolUseID string) string {
+	if config.Tracker == nil {
+		return ""
+	}
+	if call := config.Tracker.Call(toolUseID); call != nil {
+		return call.Name
+	}
+	return ""
+}
+
+// maxLineWidth はツール結果の1行に表示する最大の表示幅を返す
+func maxLineWidth(config *FilterConfig) int {
+	if config.MaxLineWidth >= 0 {
+		return config.MaxLineWidth
+	}
+	// verbose モードでは省略しない
+	if config.InfoLevel == "verbose" {
+		return 0
+	}
+	return defaultMaxLineWidth
+}
+
 // truncateOutput は出力を指定行数で省略
 func truncateOutput(s string, maxLines int) string {
 	if maxLines < 0 {
@@ -182,6 +351,29 @@ func truncateOutput(s string, maxLines int) string {
 	return result
 }
 
+// truncateLines は先頭の limit.Head 行と末尾の limit.Tail 行を残して省略
+// (テストやコンパイラの出力は末尾が重要なことが多いため)
+func truncateLines(s string, limit lineLimit) string {
+	if limit.Tail <= 0 || limit.Head < 0 {
+		return truncateOutput(s, limit.Head)
+	}
+
+	lines := strings.Split(s, "\n")
+	if len(lines) <= limit.Head+limit.Tail {
+		return s
+	}
+
+	omitted := len(lines) - limit.Head - limit.Tail
+	marker := fmt.Sprintf("… %d lines omitted …", omitted)
+	if omitted == 1 {
+		marker = "… 1 line omitted …"
+	}
+	output := append([]string{}, lines[:limit.Head]...)
+	output = append(output, marker)
+	output = append(output, lines[len(lines)-limit.Tail:]...)
+	return strings.Join(output, "\n")
+}
+
 // formatResultMessage は ResultMessage をフォーマット
 func formatResultMessage(data []byte, config *FilterConfig) (string, error) {
 	var msg ResultMessage
@@ -193,21 +385,21 @@ func formatResultMessage(data []byte, config *FilterConfig) (string, error) {
 
 	// 区切り線
 	separator := strings.Repeat("━", 40)
-	coloredSeparator := colorize(separator, "gray", config.UseColor)
+	coloredSeparator := colorize(separator, roleMuted, config.colors())
 
 	output.WriteString("\n")
 	output.WriteString(coloredSeparator)
 	output.WriteString("\n")
 
 	// 結果
-	output.WriteString(msg.Result)
+	output.WriteString(sanitizeText(msg.Result, config))
 	output.WriteString("\n")
 
 	// メトリクス (standard または verbose の場合)
 	if config.InfoLevel != "minimal" {
 		output.WriteString("\n")
 		metrics := formatMetrics(msg, config)
-		coloredMetrics := colorize(metrics, "gray", config.UseColor)
+		coloredMetrics := colorize(metrics, roleMetrics, config.colors())
 		output.WriteString(coloredMetrics)
 		output.WriteString("\n")
 	}
@@ -221,16 +413,31 @@ func formatResultMessage(data []byte, config *FilterConfig) (string, error) {
 // formatMetrics はメトリクス情報をフォーマット
 func formatMetrics(msg ResultMessage, config *FilterConfig) string {
 	var parts []string
+	for _, field := range metricFields(msg, config) {
+		parts = append(parts, field.name+": "+field.value)
+	}
+	return strings.Join(parts, " | ")
+}
+
+// metricField は表示するメトリクスの1項目
+type metricField struct {
+	name  string
+	value string
+}
+
+// metricFields は設定に応じて表示するメトリクスの項目を返す
+func metricFields(msg ResultMessage, config *FilterConfig) []metricField {
+	var fields []metricField
 
 	if config.ShowTiming || config.InfoLevel == "standard" || config.InfoLevel == "verbose" {
-		parts = append(parts, fmt.Sprintf("Duration: %.1fs", float64(msg.DurationMs)/1000.0))
+		fields = append(fields, metricField{"Duration", fmt.Sprintf("%.1fs", float64(msg.DurationMs)/1000.0)})
 	}
 
 	if config.ShowCost || config.InfoLevel == "standard" || config.InfoLevel == "verbose" {
-		parts = append(parts, fmt.Sprintf("Cost: $%.4f", msg.TotalCostUsd))
+		fields = append(fields, metricField{"Cost", fmt.Sprintf("$%.4f", msg.TotalCostUsd)})
 	}
 
-	parts = append(parts, fmt.Sprintf("Turns: %d", msg.NumTurns))
+	fields = append(fields, metricField{"Turns", fmt.Sprintf("%d", msg.NumTurns)})
 
-	return strings.Join(parts, " | ")
+	return fields
 }
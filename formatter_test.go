@@ -46,6 +46,47 @@ func TestFormatAssistantMessage_Text(t *testing.T) {
 	}
 }
 
+func TestFormatAssistantMessage_Thinking(t *testing.T) {
+	input := `{"type":"assistant","message":{"content":[{"type":"thinking","thinking":"Check the tests.\nThen \u001b[2Jfix."},{"type":"text","text":"Done"}]}}`
+
+	tests := []struct {
+		name   string
+		config FilterConfig
+		want   string
+	}{
+		{
+			name:   "standard hides thinking",
+			config: FilterConfig{ShowAssistant: true, InfoLevel: "standard"},
+			want:   "Done\n",
+		},
+		{
+			name:   "verbose shows thinking",
+			config: FilterConfig{ShowAssistant: true, InfoLevel: "verbose"},
+			want:   "Thinking:\n  Check the tests.\n  Then \\x1b[2Jfix.\nDone\n",
+		},
+		{
+			name:   "thinking role",
+			config: FilterConfig{ShowAssistant: true, InfoLevel: "verbose", UseColor: true, Theme: builtinThemes["light"], ColorDepth: depth256},
+			want: StyleItalic + "\x1b[38;5;243mThinking:" + ColorReset + "\n" +
+				"  " + StyleItalic + "\x1b[38;5;243mCheck the tests." + ColorReset + "\n" +
+				"  " + StyleItalic + "\x1b[38;5;243mThen \\x1b[2Jfix." + ColorReset + "\n" +
+				"Done\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatAssistantMessage([]byte(input), &tt.config)
+			if err != nil {
+				t.Fatalf("formatAssistantMessage() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("formatAssistantMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFormatResultMessage(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -287,3 +328,113 @@ func TestFormatUserMessage_ToolResult(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatToolResult_Latency(t *testing.T) {
+	tracker := trackLines(t, []int{0, 3}, []string{
+		`{"type":"assistant","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"make"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}}`,
+	})
+
+	tests := []struct {
+		name   string
+		result ToolResult
+		config FilterConfig
+		want   string
+	}{
+		{
+			name:   "latency shown",
+			result: ToolResult{Type: "tool_result", ToolUseID: "t1", Content: "ok"},
+			config: FilterConfig{InfoLevel: "standard", ShowLatency: true, Tracker: tracker},
+			want:   "← (3.0s) ok\n",
+		},
+		{
+			name:   "latency disabled",
+			result: ToolResult{Type: "tool_result", ToolUseID: "t1", Content: "ok"},
+			config: FilterConfig{InfoLevel: "standard", Tracker: tracker},
+			want:   "← ok\n",
+		},
+		{
+			name:   "unknown tool_use_id",
+			result: ToolResult{Type: "tool_result", ToolUseID: "t9", Content: "ok"},
+			config: FilterConfig{InfoLevel: "standard", ShowLatency: true, Tracker: tracker},
+			want:   "← ok\n",
+		},
+		{
+			name:   "no tracker",
+			result: ToolResult{Type: "tool_result", ToolUseID: "t1", Content: "ok"},
+			config: FilterConfig{InfoLevel: "standard", ShowLatency: true},
+			want:   "← ok\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatToolResult(tt.result, &tt.config); got != tt.want {
+				t.Errorf("formatToolResult() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateLines(t *testing.T) {
+	input := "l1\nl2\nl3\nl4\nl5\nl6\nl7\nl8"
+	tests := []struct {
+		name  string
+		limit lineLimit
+		want  string
+	}{
+		{name: "head only", limit: lineLimit{Head: 3}, want: "l1\nl2\nl3\n... (5 more lines)"},
+		{name: "head and tail", limit: lineLimit{Head: 2, Tail: 2}, want: "l1\nl2\n… 4 lines omitted …\nl7\nl8"},
+		{name: "fits", limit: lineLimit{Head: 4, Tail: 4}, want: input},
+		{name: "one omitted", limit: lineLimit{Head: 4, Tail: 3}, want: "l1\nl2\nl3\nl4\n… 1 line omitted …\nl6\nl7\nl8"},
+		{name: "unlimited", limit: lineLimit{Head: -1, Tail: 2}, want: input},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateLines(input, tt.limit); got != tt.want {
+				t.Errorf("truncateLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResultLimit(t *testing.T) {
+	tools := map[string]lineLimit{"Bash": {Head: 20, Tail: 10}, "Read": {Head: 3}, "Grep": {Tail: 2}}
+	tests := []struct {
+		name   string
+		config FilterConfig
+		tool   string
+		want   lineLimit
+	}{
+		{name: "standard default", config: FilterConfig{InfoLevel: "standard"}, want: lineLimit{Head: defaultMaxLines}},
+		{name: "verbose default", config: FilterConfig{InfoLevel: "verbose"}, want: lineLimit{Head: -1}},
+		{name: "max and tail", config: FilterConfig{InfoLevel: "standard", MaxLines: 8, TailLines: 4}, want: lineLimit{Head: 8, Tail: 4}},
+		{name: "explicit limit in verbose", config: FilterConfig{InfoLevel: "verbose", MaxLines: 8}, want: lineLimit{Head: 8}},
+		{name: "tool override", config: FilterConfig{InfoLevel: "standard", MaxLines: 8, TailLines: 4, ToolLines: tools}, tool: "Bash", want: lineLimit{Head: 20, Tail: 10}},
+		{name: "tool override without tail", config: FilterConfig{InfoLevel: "standard", TailLines: 4, ToolLines: tools}, tool: "Read", want: lineLimit{Head: 3}},
+		{name: "tool override keeps head", config: FilterConfig{InfoLevel: "standard", MaxLines: 8, ToolLines: tools}, tool: "Grep", want: lineLimit{Head: 8, Tail: 2}},
+		{name: "other tool", config: FilterConfig{InfoLevel: "standard", ToolLines: tools}, tool: "Glob", want: lineLimit{Head: defaultMaxLines}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resultLimit(&tt.config, tt.tool); got != tt.want {
+				t.Errorf("resultLimit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatToolResult_ToolLines(t *testing.T) {
+	tracker := trackLines(t, []int{0}, []string{
+		`{"type":"assistant","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go test ./..."}}]}}`,
+	})
+	config := &FilterConfig{InfoLevel: "standard", Tracker: tracker, ToolLines: map[string]lineLimit{"Bash": {Head: 1, Tail: 2}}}
+	result := ToolResult{ToolUseID: "t1", Content: "=== RUN TestA\n--- PASS: TestA\n=== RUN TestB\n--- FAIL: TestB\nFAIL\nexit status 1"}
+
+	want := "← === RUN TestA\n… 3 lines omitted …\nFAIL\nexit status 1\n"
+	if got := formatToolResult(result, config); got != want {
+		t.Errorf("formatToolResult() = %q, want %q", got, want)
+	}
+}
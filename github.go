@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// githubActions は GitHub Actions のワークフローコマンドを出力するための状態
+type githubActions struct {
+	turnID    string          // 現在のターンの message id
+	turns     int             // 開始したターンの数
+	inTurn    bool            // ターンの途中か
+	title     string          // 現在のターンの group のタイトル
+	annotated map[string]bool // ::error:: を出力したツール呼び出しの tool_use_id
+	group     *githubGroup    // 出力中で開いている group
+}
+
+// githubGroup は出力中で開いている group の持ち主
+// group は入れ子にできないので、複数入力では全ストリームで共有し、同時に1つだけ開く
+type githubGroup struct {
+	owner *stream
+}
+
+// newGitHubActions は group の状態を共有する githubActions を作成
+func newGitHubActions(group *githubGroup) *githubActions {
+	return &githubActions{annotated: make(map[string]bool), group: group}
+}
+
+// githubBefore はメッセージの出力前に group の開始・終了を出力
+func (s *stream) githubBefore(msgType string, data []byte) {
+	g := s.github
+	switch msgType {
+	case "assistant":
+		var msg AssistantMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return
+		}
+		// 同じ message id のチャンクは同じ group に入れる
+		if g.inTurn && msg.Message.ID != "" && msg.Message.ID == g.turnID {
+			s.githubResumeGroup()
+			return
+		}
+		s.githubEndGroup()
+		g.turns++
+		g.turnID = msg.Message.ID
+		g.title = fmt.Sprintf("Turn %d", g.turns)
+		if details := joinNonEmpty(", ", s.label, msg.Message.Model, subagentLabel(msg.ParentToolUseID)); details != "" {
+			g.title += " (" + details + ")"
+		}
+		s.githubOpenGroup(g.title)
+		g.inTurn = true
+	case "user":
+		if g.inTurn {
+			s.githubResumeGroup()
+		}
+	case "result":
+		s.githubEndGroup()
+	}
+}
+
+// githubOpenGroup は group を開く (他のストリームの group が開いていれば先に閉じる)
+func (s *stream) githubOpenGroup(title string) {
+	if owner := s.github.group.owner; owner != nil && owner != s {
+		owner.command("endgroup", nil, "")
+	}
+	s.command("group", nil, title)
+	s.github.group.owner = s
+}
+
+// githubResumeGroup は他のストリームの出力で閉じられたターンの group を開き直す
+func (s *stream) githubResumeGroup() {
+	if s.github.group.owner != s {
+		s.githubOpenGroup(s.github.title + " (continued)")
+	}
+}
+
+// githubAfter はメッセージの出力後にアノテーションとジョブサマリーを出力
+func (s *stream) githubAfter(msgType string, data []byte) {
+	switch msgType {
+	case "user":
+		var msg UserMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return
+		}
+		for _, result := range msg.Message.Content {
+			if result.Type != "tool_result" || !result.IsError {
+				continue
+			}
+			name, input := "Tool", json.RawMessage(nil)
+			if call := s.config.Tracker.Call(result.ToolUseID); call != nil {
+				name, input = call.Name, call.Input
+			}
+			s.githubError(name+" failed", input, truncateLines(sanitizeText(result.Content, s.config), resultLimit(s.config, name)))
+			s.github.annotated[result.ToolUseID] = true
+		}
+	case "result":
+		var msg ResultMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return
+		}
+		for _, denial := range msg.PermissionDenials {
+			// 拒否されたツール結果に出力済みのアノテーションは繰り返さない
+			if denial.ToolUseID != "" && s.github.annotated[denial.ToolUseID] {
+				continue
+			}
+			s.github.annotated[denial.ToolUseID] = true
+			s.githubError("Permission denied: "+denial.ToolName, denial.ToolInput,
+				fmt.Sprintf("%s was not permitted to run", denial.ToolName))
+		}
+		if err := s.writeStepSummary(msg); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write step summary: %v\n", err)
+		}
+	}
+}
+
+// githubEndGroup はこのストリームのターンを終え、開いている group を閉じる
+func (s *stream) githubEndGroup() {
+	if s.github.group.owner == s {
+		s.command("endgroup", nil, "")
+		s.github.group.owner = nil
+	}
+	s.github.inTurn = false
+}
+
+// githubError は ::error:: アノテーションを出力 (ツールの入力に file_path があれば file= を付ける)
+func (s *stream) githubError(title string, input json.RawMessage, message string) {
+	var props []string
+	if file := annotationFile(input); file != "" {
+		props = append(props, "file="+escapeProperty(file))
+	}
+	props = append(props, "title="+escapeProperty(title))
+	s.command("error", props, message)
+}
+
+// command はワークフローコマンドを出力
+// ラベルを付けると GitHub Actions に解釈されないため、常に行頭から出力する
+func (s *stream) command(name string, props []string, message string) {
+	line := "::" + name
+	if len(props) > 0 {
+		line += " " + strings.Join(props, ",")
+	}
+	fmt.Fprintf(s.output, "%s::%s\n", line, escapeData(message))
+}
+
+// writeStepSummary は最終結果とメトリクスを $GITHUB_STEP_SUMMARY に Markdown で追記
+func (s *stream) writeStepSummary(msg ResultMessage) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var output strings.Builder
+	output.WriteString("## Claude result")
+	if s.prefixed {
+		output.WriteString(" (" + s.label + ")")
+	}
+	output.WriteString("\n\n")
+	output.WriteString(msg.Result)
+	output.WriteString("\n\n")
+	// ジョブサマリーには情報レベルに関わらずメトリクスを載せる
+	output.WriteString(markdownMetricsTable(msg, &FilterConfig{InfoLevel: "standard"}))
+	if n := len(msg.PermissionDenials); n > 0 {
+		output.WriteString(fmt.Sprintf("Permission denials: %d\n\n", n))
+	}
+
+	_, err = f.WriteString(output.String())
+	return err
+}
+
+// subagentLabel はサブエージェントのターンであれば "subagent" を返す
+func subagentLabel(parentToolUseID string) string {
+	if parentToolUseID != "" {
+		return "subagent"
+	}
+	return ""
+}
+
+// annotationFile はツールの入力からアノテーションを付けるファイルを取り出す
+// $GITHUB_WORKSPACE 以下の絶対パスはリポジトリからの相対パスにする
+func annotationFile(input json.RawMessage) string {
+	var params struct {
+		FilePath     string `json:"file_path"`
+		NotebookPath string `json:"notebook_path"`
+	}
+	if err := json.Unmarshal(input, &params); err != nil {
+		return ""
+	}
+	path := params.FilePath
+	if path == "" {
+		path = params.NotebookPath
+	}
+
+	if workspace := os.Getenv("GITHUB_WORKSPACE"); workspace != "" && filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(workspace, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return path
+}
+
+// stopCommands はツールの出力やモデルのテキストに含まれるワークフローコマンドを無効にする
+// 行頭 (空白を除く) が :: または ##[ の行があれば、推測できないトークンの ::stop-commands:: で囲む
+// (囲まないと ::add-mask:: や ::stop-commands:: で ccfilter 自身の group やアノテーションまで乗っ取られる)
+func stopCommands(text string) string {
+	if !hasWorkflowCommand(text) {
+		return text
+	}
+	token := randomHex(16)
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return "::stop-commands::" + token + "\n" + text + "::" + token + "::\n"
+}
+
+// hasWorkflowCommand は GitHub Actions がワークフローコマンドとして解釈する行があるかどうかを判定
+func hasWorkflowCommand(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(line, "::") || strings.HasPrefix(line, "##[") {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeData はワークフローコマンドのメッセージをエスケープ
+func escapeData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeProperty はワークフローコマンドのプロパティ値をエスケープ
+func escapeProperty(s string) string {
+	s = escapeData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessInput_GitHubActions(t *testing.T) {
+	summary := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", summary)
+	t.Setenv("GITHUB_WORKSPACE", "/work")
+
+	input := strings.Join([]string{
+		`{"type":"system","subtype":"init","session_id":"s1","model":"sonnet"}`,
+		`{"type":"assistant","message":{"id":"m1","model":"sonnet","content":[{"type":"text","text":"Editing"}]}}`,
+		`{"type":"assistant","message":{"id":"m1","model":"sonnet","content":[{"type":"tool_use","id":"t1","name":"Edit","input":{"file_path":"/work/src/a.go","old_string":"a","new_string":"b"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"String not found:\n100% a","is_error":true}]}}`,
+		`{"type":"assistant","message":{"id":"m2","model":"haiku","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"ls"}}]},"parent_tool_use_id":"task1"}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t2","content":"a.go"}]},"parent_tool_use_id":"task1"}`,
+		`{"type":"result","subtype":"success","result":"Done","duration_ms":2000,"total_cost_usd":0.01,"num_turns":2,"session_id":"s1","permission_denials":[{"tool_name":"Write","tool_use_id":"t3","tool_input":{"file_path":"/tmp/b.go"}}]}`,
+	}, "\n")
+
+	config := NewFilterConfig()
+	config.UseColor = false
+	config.InfoLevel = "minimal"
+	config.GitHubActions = true
+
+	var output bytes.Buffer
+	if err := processInput(strings.NewReader(input), &output, config); err != nil {
+		t.Fatalf("processInput() error = %v", err)
+	}
+
+	var commands []string
+	for _, line := range strings.Split(output.String(), "\n") {
+		if strings.HasPrefix(line, "::") {
+			commands = append(commands, line)
+		}
+	}
+	want := []string{
+		"::group::Turn 1 (sonnet)",
+		"::error file=src/a.go,title=Edit failed::String not found:%0A100%25 a",
+		"::endgroup::",
+		"::group::Turn 2 (haiku, subagent)",
+		"::endgroup::",
+		"::error file=/tmp/b.go,title=Permission denied%3A Write::Write was not permitted to run",
+	}
+	if len(commands) != len(want) {
+		t.Fatalf("workflow commands =\n%s\nwant:\n%s", strings.Join(commands, "\n"), strings.Join(want, "\n"))
+	}
+	for i := range want {
+		if commands[i] != want[i] {
+			t.Errorf("command[%d] = %q, want %q", i, commands[i], want[i])
+		}
+	}
+
+	data, err := os.ReadFile(summary)
+	if err != nil {
+		t.Fatalf("step summary was not written: %v", err)
+	}
+	for _, want := range []string{"## Claude result", "Done", "| Duration | Cost | Turns |", "| 2.0s | $0.0100 | 2 |", "Permission denials: 1"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("step summary does not contain %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestProcessInput_GitHubActionsOtherFormat(t *testing.T) {
+	config := NewFilterConfig()
+	config.Format = "json"
+	config.GitHubActions = true
+
+	var output bytes.Buffer
+	input := `{"type":"assistant","message":{"id":"m1","content":[{"type":"text","text":"hi"}]}}`
+	if err := processInput(strings.NewReader(input), &output, config); err != nil {
+		t.Fatalf("processInput() error = %v", err)
+	}
+	if strings.Contains(output.String(), "::group::") {
+		t.Errorf("workflow commands should only be emitted in text format, got %q", output.String())
+	}
+}
+
+func TestParseArgs_GitHubActions(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		want bool
+	}{
+		{name: "outside actions", env: "", args: []string{"cmd"}, want: false},
+		{name: "auto enabled", env: "true", args: []string{"cmd"}, want: true},
+		{name: "explicitly disabled", env: "true", args: []string{"cmd", "--github-actions=false"}, want: false},
+		{name: "explicitly enabled", env: "", args: []string{"cmd", "--github-actions"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_ACTIONS", tt.env)
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+			os.Args = tt.args
+
+			got, err := parseArgs()
+			if err != nil {
+				t.Fatalf("parseArgs() error = %v", err)
+			}
+			if got.GitHubActions != tt.want {
+				t.Errorf("GitHubActions = %v, want %v", got.GitHubActions, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeWorkflowCommand(t *testing.T) {
+	if got, want := escapeData("50% done\r\nnext"), "50%25 done%0D%0Anext"; got != want {
+		t.Errorf("escapeData() = %q, want %q", got, want)
+	}
+	if got, want := escapeProperty("a:b,c"), "a%3Ab%2Cc"; got != want {
+		t.Errorf("escapeProperty() = %q, want %q", got, want)
+	}
+}
+
+func TestProcessInput_GitHubActionsDeniedOnce(t *testing.T) {
+	t.Setenv("GITHUB_STEP_SUMMARY", "")
+	input := strings.Join([]string{
+		`{"type":"assistant","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"Write","input":{"file_path":"/tmp/a.go"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"Claude requested permissions to write to /tmp/a.go","is_error":true}]}}`,
+		`{"type":"result","subtype":"success","result":"Done","permission_denials":[{"tool_name":"Write","tool_use_id":"t1","tool_input":{"file_path":"/tmp/a.go"}},{"tool_name":"Bash","tool_use_id":"t2","tool_input":{"command":"rm -rf /"}}]}`,
+	}, "\n")
+
+	config := NewFilterConfig()
+	config.UseColor = false
+	config.GitHubActions = true
+
+	var output bytes.Buffer
+	if err := processInput(strings.NewReader(input), &output, config); err != nil {
+		t.Fatalf("processInput() error = %v", err)
+	}
+	if n := strings.Count(output.String(), "::error file=/tmp/a.go"); n != 1 {
+		t.Errorf("denied Write annotated %d times, want 1:\n%s", n, output.String())
+	}
+	if !strings.Contains(output.String(), "::error title=Permission denied%3A Bash::") {
+		t.Errorf("denial without a tool result is not annotated:\n%s", output.String())
+	}
+}
+
+func TestGitHubActions_GroupsWithMultipleInputs(t *testing.T) {
+	t.Setenv("GITHUB_STEP_SUMMARY", "")
+	config := NewFilterConfig()
+	config.UseColor = false
+	config.InfoLevel = "minimal"
+	config.GitHubActions = true
+
+	var output bytes.Buffer
+	group := &githubGroup{}
+	streams := make(map[string]*stream)
+	for _, name := range []string{"api", "web"} {
+		s := newStream(&output, config)
+		s.prefixed = true
+		s.name = name
+		s.github.group = group
+		streams[name] = s
+	}
+
+	// 2つのセッションのターンが交互に届く
+	lines := []struct{ stream, line string }{
+		{"api", `{"type":"assistant","message":{"id":"a1","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"ls"}}]}}`},
+		{"web", `{"type":"assistant","message":{"id":"w1","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"pwd"}}]}}`},
+		{"api", `{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"a.go"}]}}`},
+		{"web", `{"type":"result","subtype":"success","result":"Done"}`},
+		{"api", `{"type":"result","subtype":"success","result":"Done"}`},
+	}
+	for _, l := range lines {
+		streams[l.stream].processLine(l.line, time.Time{})
+	}
+	for _, s := range streams {
+		s.finish()
+	}
+
+	var commands []string
+	depth := 0
+	for _, line := range strings.Split(output.String(), "\n") {
+		switch {
+		case strings.HasPrefix(line, "::group::"):
+			depth++
+		case strings.HasPrefix(line, "::endgroup::"):
+			depth--
+		default:
+			continue
+		}
+		if depth < 0 || depth > 1 {
+			t.Fatalf("groups are not paired (depth %d):\n%s", depth, output.String())
+		}
+		commands = append(commands, line)
+	}
+	want := []string{
+		"::group::Turn 1 (api)",
+		"::endgroup::",
+		"::group::Turn 1 (web)",
+		"::endgroup::",
+		"::group::Turn 1 (api) (continued)",
+		"::endgroup::",
+	}
+	if strings.Join(commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("workflow commands =\n%s\nwant:\n%s", strings.Join(commands, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// runWorkflowCommands は GitHub Actions のランナーと同様に出力を読み、実行されるワークフローコマンドを返す
+func runWorkflowCommands(output string) []string {
+	var commands []string
+	stopped := ""
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimLeft(line, " \t")
+		if !strings.HasPrefix(line, "::") {
+			continue
+		}
+		if stopped != "" {
+			if line == "::"+stopped+"::" {
+				stopped = ""
+			}
+			continue
+		}
+		if token, ok := strings.CutPrefix(line, "::stop-commands::"); ok {
+			stopped = token
+		}
+		commands = append(commands, line)
+	}
+	return commands
+}
+
+func TestProcessInput_GitHubActionsInjection(t *testing.T) {
+	input := strings.Join([]string{
+		`{"type":"assistant","message":{"id":"m1","model":"sonnet","content":[{"type":"text","text":"::error::fake"},{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"cat log"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"::add-mask::secret\n  ::stop-commands::abc\n##[error]legacy"}]}}`,
+		`{"type":"assistant","message":{"id":"m2","model":"sonnet","content":[{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"/work/a.go"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t2","content":"not found","is_error":true}]}}`,
+		`{"type":"result","subtype":"success","result":"Done","session_id":"s1"}`,
+	}, "\n")
+
+	config := NewFilterConfig()
+	config.UseColor = false
+	config.InfoLevel = "verbose"
+	config.ShowAssistant = true
+	config.GitHubActions = true
+
+	var output bytes.Buffer
+	if err := processInput(strings.NewReader(input), &output, config); err != nil {
+		t.Fatalf("processInput() error = %v", err)
+	}
+
+	var own []string
+	for _, command := range runWorkflowCommands(output.String()) {
+		if !strings.HasPrefix(command, "::stop-commands::") {
+			own = append(own, command)
+		}
+	}
+	want := []string{
+		"::group::Turn 1 (sonnet)",
+		"::endgroup::",
+		"::group::Turn 2 (sonnet)",
+		"::error file=/work/a.go,title=Read failed::not found",
+		"::endgroup::",
+	}
+	if strings.Join(own, "\n") != strings.Join(want, "\n") {
+		t.Errorf("executed workflow commands =\n%s\nwant:\n%s\n\noutput:\n%s", strings.Join(own, "\n"), strings.Join(want, "\n"), output.String())
+	}
+	for _, text := range []string{"::error::fake", "::add-mask::secret", "::stop-commands::abc", "##[error]legacy"} {
+		if !strings.Contains(output.String(), text) {
+			t.Errorf("output should still show %q:\n%s", text, output.String())
+		}
+	}
+}
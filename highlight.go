@@ -0,0 +1,247 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// langSpec はシンタックスハイライトのための言語ごとの字句の定義
+type langSpec struct {
+	keywords     map[string]bool
+	lineComments []string  // 行コメントの開始
+	blockComment [2]string // ブロックコメントの開始と終了
+	quotes       string    // 文字列を囲む文字
+	multiQuotes  []string  // 複数行にわたる文字列 (開始と終了が同じ)
+	keyColor     bool      // "key": や key: をキーとして色付けする (JSON/YAML)
+	variables    bool      // $VAR を変数として色付けする (shell)
+}
+
+// シンタックスハイライトの役割
+const (
+	hlKeyword  = roleKeyword
+	hlString   = roleString
+	hlComment  = roleComment
+	hlNumber   = roleNumber
+	hlKey      = roleKey
+	hlVariable = roleVariable
+	hlHeading  = roleHeading
+)
+
+func keywordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// langSpecs は対応している言語
+var langSpecs = map[string]*langSpec{
+	"go": {
+		keywords: keywordSet(`break case chan const continue default defer else fallthrough for func go goto if import
+			interface map package range return select struct switch type var nil true false iota`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"'`,
+		multiQuotes:  []string{"`"},
+	},
+	"python": {
+		keywords: keywordSet(`and as assert async await break class continue def del elif else except finally for from
+			global if import in is lambda nonlocal not or pass raise return try while with yield None True False self`),
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		multiQuotes:  []string{`"""`, `'''`},
+	},
+	"javascript": {
+		keywords: keywordSet(`async await break case catch class const continue debugger default delete do else export
+			extends finally for from function if import in instanceof interface let new of return static super switch
+			this throw try type typeof var void while yield null undefined true false enum implements private public
+			protected readonly`),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"'`,
+		multiQuotes:  []string{"`"},
+	},
+	"shell": {
+		keywords: keywordSet(`if then else elif fi for while until do done case esac function in return local export
+			readonly set unset exit echo cd source`),
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		variables:    true,
+	},
+	"json": {
+		keywords: keywordSet(`true false null`),
+		quotes:   `"`,
+		keyColor: true,
+	},
+	"yaml": {
+		keywords:     keywordSet(`true false null yes no on off`),
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		keyColor:     true,
+	},
+	"markdown": {},
+}
+
+// langAliases は拡張子やフェンスの info string から言語への対応
+var langAliases = map[string]string{
+	"go": "go", "golang": "go",
+	"py": "python", "python": "python",
+	"js": "javascript", "jsx": "javascript", "mjs": "javascript", "cjs": "javascript", "javascript": "javascript",
+	"ts": "javascript", "tsx": "javascript", "typescript": "javascript",
+	"sh": "shell", "bash": "shell", "zsh": "shell", "shell": "shell", "console": "shell",
+	"json": "json", "jsonl": "json",
+	"yaml": "yaml", "yml": "yaml",
+	"md": "markdown", "markdown": "markdown",
+}
+
+// detectLanguage はフェンスの info string から言語を判定 (対応していない場合は空)
+func detectLanguage(info string) string {
+	return langAliases[strings.ToLower(strings.TrimSpace(info))]
+}
+
+// languageFromPath はファイルの拡張子から言語を判定
+func languageFromPath(path string) string {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	return detectLanguage(ext)
+}
+
+// highlighter は複数行にわたるコメントや文字列の状態を保持しながら1行ずつ色付けする
+type highlighter struct {
+	spec    *langSpec
+	lang    string
+	colors  *palette
+	closing string // 複数行のコメントまたは文字列の終了 (空の場合はその中にいない)
+	color   string
+}
+
+// newHighlighter は lang の highlighter を作成 (対応していない言語やカラー出力が無効な場合は何もしない)
+func newHighlighter(lang string, colors *palette) *highlighter {
+	return &highlighter{spec: langSpecs[lang], lang: lang, colors: colors}
+}
+
+// highlightCode は複数行のコードを色付け
+func highlightCode(code, lang string, colors *palette) string {
+	h := newHighlighter(lang, colors)
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		lines[i] = h.line(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// line は1行を色付け (色は行ごとに閉じるため、行単位で省略しても崩れない)
+func (h *highlighter) line(line string) string {
+	if h.colors == nil || h.spec == nil {
+		return line
+	}
+	if h.lang == "markdown" {
+		if strings.HasPrefix(line, "#") {
+			return colorize(line, hlHeading, h.colors)
+		}
+		return line
+	}
+
+	var output strings.Builder
+	rest := line
+
+	// 前の行から続くコメントや文字列
+	if h.closing != "" {
+		end := strings.Index(rest, h.closing)
+		if end < 0 {
+			return colorize(rest, h.color, h.colors)
+		}
+		end += len(h.closing)
+		output.WriteString(colorize(rest[:end], h.color, h.colors))
+		rest = rest[end:]
+		h.closing = ""
+	}
+
+	for rest != "" {
+		n, color := h.token(rest)
+		output.WriteString(colorize(rest[:n], color, h.colors))
+		rest = rest[n:]
+	}
+	return output.String()
+}
+
+// token は rest の先頭のトークンの長さと色を返す
+func (h *highlighter) token(rest string) (int, string) {
+	spec := h.spec
+
+	for _, c := range spec.lineComments {
+		if strings.HasPrefix(rest, c) {
+			return len(rest), hlComment
+		}
+	}
+	if open := spec.blockComment[0]; open != "" && strings.HasPrefix(rest, open) {
+		return h.span(rest, len(open), spec.blockComment[1], hlComment)
+	}
+	for _, q := range spec.multiQuotes {
+		if strings.HasPrefix(rest, q) {
+			return h.span(rest, len(q), q, hlString)
+		}
+	}
+	if strings.ContainsRune(spec.quotes, rune(rest[0])) {
+		n := quotedLength(rest)
+		if spec.keyColor && strings.HasPrefix(strings.TrimLeft(rest[n:], " "), ":") {
+			return n, hlKey
+		}
+		return n, hlString
+	}
+	if spec.variables && rest[0] == '$' {
+		if m := shellVariablePattern.FindString(rest); m != "" {
+			return len(m), hlVariable
+		}
+	}
+	if spec.keyColor {
+		if m := yamlKeyPattern.FindString(rest); m != "" {
+			return len(m) - 1, hlKey
+		}
+	}
+	if m := numberPattern.FindString(rest); m != "" {
+		return len(m), hlNumber
+	}
+	// 識別子はまとめて読み、途中の数字を数値として扱わない
+	if m := identPattern.FindString(rest); m != "" {
+		if spec.keywords[m] {
+			return len(m), hlKeyword
+		}
+		return len(m), ""
+	}
+
+	_, size := utf8.DecodeRuneInString(rest)
+	return size, ""
+}
+
+// span は close で終わるコメントや文字列の長さを返す (行内で終わらない場合は次の行へ続ける)
+func (h *highlighter) span(rest string, skip int, close, color string) (int, string) {
+	if end := strings.Index(rest[skip:], close); end >= 0 {
+		return skip + end + len(close), color
+	}
+	h.closing, h.color = close, color
+	return len(rest), color
+}
+
+var (
+	numberPattern        = regexp.MustCompile(`^(0[xX][0-9a-fA-F_]+|\d[\d_]*(\.\d+)?([eE][+-]?\d+)?)`)
+	identPattern         = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_]*`)
+	yamlKeyPattern       = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_.-]*:`)
+	shellVariablePattern = regexp.MustCompile(`^\$(\{[^}]*\}|[A-Za-z_][A-Za-z0-9_]*|[0-9@#?*$!-])`)
+)
+
+// quotedLength は引用符で始まる文字列の長さを返す (エスケープを考慮し、閉じていなければ行末まで)
+func quotedLength(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlightCode(t *testing.T) {
+	kw := func(s string) string { return colorize(s, hlKeyword, defaultColors) }
+	str := func(s string) string { return colorize(s, hlString, defaultColors) }
+	com := func(s string) string { return colorize(s, hlComment, defaultColors) }
+	num := func(s string) string { return colorize(s, hlNumber, defaultColors) }
+	key := func(s string) string { return colorize(s, hlKey, defaultColors) }
+
+	tests := []struct {
+		name string
+		lang string
+		code string
+		want string
+	}{
+		{
+			name: "go",
+			lang: "go",
+			code: `func f() int { return 42 } // done`,
+			want: kw("func") + " f() int { " + kw("return") + " " + num("42") + " } " + com("// done"),
+		},
+		{
+			name: "go block comment and raw string across lines",
+			lang: "go",
+			code: "/* a\nb */ x := `c\nd`",
+			want: com("/* a") + "\n" + com("b */") + " x := " + str("`c") + "\n" + str("d`"),
+		},
+		{
+			name: "identifiers containing digits are not numbers",
+			lang: "go",
+			code: `v2 := x1`,
+			want: `v2 := x1`,
+		},
+		{
+			name: "python",
+			lang: "python",
+			code: `def f(self): return 'a\'b'  # note`,
+			want: kw("def") + " f(" + kw("self") + "): " + kw("return") + " " + str(`'a\'b'`) + "  " + com("# note"),
+		},
+		{
+			name: "python triple quotes",
+			lang: "python",
+			code: "\"\"\"doc\nmore\"\"\" pass",
+			want: str(`"""doc`) + "\n" + str(`more"""`) + " " + kw("pass"),
+		},
+		{
+			name: "javascript",
+			lang: "javascript",
+			code: "const s = `x`; // y",
+			want: kw("const") + " s = " + str("`x`") + "; " + com("// y"),
+		},
+		{
+			name: "shell",
+			lang: "shell",
+			code: `if [ -n "$X" ]; then echo ${HOME}; fi # c`,
+			want: kw("if") + " [ -n " + str(`"$X"`) + " ]; " + kw("then") + " " + kw("echo") + " " + colorize("${HOME}", hlVariable, defaultColors) + "; " + kw("fi") + " " + com("# c"),
+		},
+		{
+			name: "json",
+			lang: "json",
+			code: `{"a": "b", "n": 1.5, "t": true}`,
+			want: `{` + key(`"a"`) + `: ` + str(`"b"`) + `, ` + key(`"n"`) + `: ` + num("1.5") + `, ` + key(`"t"`) + `: ` + kw("true") + `}`,
+		},
+		{
+			name: "yaml",
+			lang: "yaml",
+			code: "runs-on: ubuntu # ci\n- name: 'x'",
+			want: key("runs-on") + ": ubuntu " + com("# ci") + "\n- " + key("name") + ": " + str("'x'"),
+		},
+		{
+			name: "markdown",
+			lang: "markdown",
+			code: "# Title\ntext",
+			want: colorize("# Title", hlHeading, defaultColors) + "\ntext",
+		},
+		{
+			name: "unknown language",
+			lang: "",
+			code: `func x() {}`,
+			want: `func x() {}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightCode(tt.code, tt.lang, defaultColors); got != tt.want {
+				t.Errorf("highlightCode() =\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightCode_Disabled(t *testing.T) {
+	code := "func main() {\n\treturn \"x\"\n}"
+	if got := highlightCode(code, "go", nil); got != code {
+		t.Errorf("highlightCode() with color disabled = %q", got)
+	}
+}
+
+func TestLanguageDetection(t *testing.T) {
+	paths := map[string]string{
+		"main.go":        "go",
+		"/src/app.py":    "python",
+		"web/index.tsx":  "javascript",
+		"lib.mjs":        "javascript",
+		"deploy.sh":      "shell",
+		"package.json":   "json",
+		".github/ci.yml": "yaml",
+		"README.md":      "markdown",
+		"Makefile":       "",
+		"archive.tar.gz": "",
+	}
+	for path, want := range paths {
+		if got := languageFromPath(path); got != want {
+			t.Errorf("languageFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	infos := map[string]string{"golang": "go", "TypeScript": "javascript", "bash": "shell", "yml": "yaml", "diff": ""}
+	for info, want := range infos {
+		if got := detectLanguage(info); got != want {
+			t.Errorf("detectLanguage(%q) = %q, want %q", info, got, want)
+		}
+	}
+}
+
+func TestFormatToolResult_HighlightRead(t *testing.T) {
+	tracker := trackLines(t, []int{0}, []string{
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"main.go"}}]}}`,
+	})
+	result := ToolResult{Type: "tool_result", ToolUseID: "t1", Content: "     1→package main\n     2→"}
+
+	got := formatToolResult(result, &FilterConfig{InfoLevel: "standard", UseColor: true, Tracker: tracker})
+	want := colorize("←", "cyan", defaultColors) + " " +
+		colorize("     1→", "gray", defaultColors) + colorize("package", hlKeyword, defaultColors) + " main\n" +
+		colorize("     2→", "gray", defaultColors) + "\n"
+	if got != want {
+		t.Errorf("formatToolResult() = %q, want %q", got, want)
+	}
+
+	plain := formatToolResult(result, &FilterConfig{InfoLevel: "standard", UseColor: false, Tracker: tracker})
+	if strings.Contains(plain, "\x1b[") {
+		t.Errorf("formatToolResult() without color = %q", plain)
+	}
+}
+
+func TestFormatToolUse_VerboseEditDiff(t *testing.T) {
+	content := Content{
+		Type:  "tool_use",
+		Name:  "Edit",
+		Input: []byte(`{"file_path":"a.py","old_string":"x = 1","new_string":"x = 2\ny = 3"}`),
+	}
+
+	got := formatToolUse(content, &FilterConfig{InfoLevel: "verbose", UseColor: false})
+	want := "→ Edit: file_path=\"a.py\"\n  - x = 1\n  + x = 2\n  + y = 3\n"
+	if got != want {
+		t.Errorf("formatToolUse() = %q, want %q", got, want)
+	}
+
+	standard := formatToolUse(content, &FilterConfig{InfoLevel: "standard", UseColor: false})
+	if standard != "→ Edit: file_path=\"a.py\"\n" {
+		t.Errorf("standard mode should not show the diff, got %q", standard)
+	}
+}
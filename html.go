@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// htmlSession は HTML レポートに出力する1セッション分のデータ
+type htmlSession struct {
+	Title  string
+	Init   *SystemMessage
+	Result *ResultMessage
+	Stats  Stats
+	Nodes  []*htmlNode
+}
+
+// htmlNode はターン・ツール呼び出し・サブエージェントのツリーの1ノード
+type htmlNode struct {
+	Step     *Step
+	Children []*htmlNode
+}
+
+// diffLine は Edit/Write の差分表示の1行
+type diffLine struct {
+	Op   string // "add", "del"
+	Text string
+}
+
+// writeHTMLReport は全セッションを1つの静的 HTML として書き出す
+func writeHTMLReport(w io.Writer, streams []*stream) error {
+	var sessions []htmlSession
+	for _, s := range streams {
+		sessions = append(sessions, newHTMLSession(s.config.Tracker))
+	}
+	return htmlTemplate.Execute(w, sessions)
+}
+
+// newHTMLSession は Tracker からレポート用のデータを作成
+func newHTMLSession(t *Tracker) htmlSession {
+	session := htmlSession{
+		Title:  "Claude session",
+		Init:   t.Init,
+		Result: t.Result,
+		Stats:  computeStats(t),
+	}
+	if session.Stats.SessionID != "" {
+		session.Title += " " + session.Stats.SessionID
+	}
+
+	for _, step := range t.Steps {
+		if step.Kind == "turn" && step.ParentID == "" {
+			session.Nodes = append(session.Nodes, buildHTMLNode(t, step))
+		}
+	}
+	return session
+}
+
+// buildHTMLNode は step 以下のツリーを作成
+func buildHTMLNode(t *Tracker, step *Step) *htmlNode {
+	node := &htmlNode{Step: step}
+	for _, child := range t.children(step) {
+		node.Children = append(node.Children, buildHTMLNode(t, child))
+	}
+	return node
+}
+
+// editDiff は Edit/MultiEdit/Write の入力から差分の行を作成
+func editDiff(step *Step) []diffLine {
+	var input struct {
+		OldString string `json:"old_string"`
+		NewString string `json:"new_string"`
+		Content   string `json:"content"`
+		Edits     []struct {
+			OldString string `json:"old_string"`
+			NewString string `json:"new_string"`
+		} `json:"edits"`
+	}
+	if err := json.Unmarshal(step.Input, &input); err != nil {
+		return nil
+	}
+
+	var lines []diffLine
+	add := func(op, text string) {
+		if text == "" {
+			return
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+			lines = append(lines, diffLine{Op: op, Text: line})
+		}
+	}
+
+	switch step.Name {
+	case "Edit":
+		add("del", input.OldString)
+		add("add", input.NewString)
+	case "MultiEdit":
+		for _, edit := range input.Edits {
+			add("del", edit.OldString)
+			add("add", edit.NewString)
+		}
+	case "Write":
+		add("add", input.Content)
+	}
+	return lines
+}
+
+// prettyJSON はツールの入力を整形
+func prettyJSON(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
+// formatSeconds は所要時間を秒でフォーマット
+func formatSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": func(step *Step) string { return formatSeconds(step.Duration()) },
+	"ms":       func(ms int) string { return formatSeconds(time.Duration(ms) * time.Millisecond) },
+	"params":   func(step *Step) string { return extractMainParams(step.Name, step.Input) },
+	"input":    func(step *Step) string { return prettyJSON(step.Input) },
+	"diff":     editDiff,
+	"cost":     func(usd float64) string { return fmt.Sprintf("$%.4f", usd) },
+	"prefix": func(op string) string {
+		if op == "del" {
+			return "-"
+		}
+		return "+"
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>ccfilter report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; color: #1f2328; background: #fff; }
+header { position: sticky; top: 0; background: #f6f8fa; border-bottom: 1px solid #d0d7de; padding: 8px 16px; display: flex; gap: 8px; align-items: center; }
+header h1 { font-size: 16px; margin: 0 auto 0 0; }
+main { padding: 16px; }
+section.session { margin-bottom: 32px; }
+h2 { font-size: 18px; }
+table { border-collapse: collapse; margin: 8px 0; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: left; font-size: 13px; }
+th { background: #f6f8fa; }
+details.node { border-left: 2px solid #d0d7de; margin: 4px 0 4px 8px; padding-left: 8px; }
+details.node > summary { cursor: pointer; padding: 2px 0; }
+details.turn > summary .kind { color: #6e7781; }
+details.tool > summary .tool-name { color: #0550ae; font-weight: bold; }
+details.subagent { border-left-color: #8250df; }
+details.error { border-left-color: #cf222e; background: #fff5f5; }
+.badge { background: #cf222e; color: #fff; border-radius: 8px; padding: 0 6px; font-size: 12px; }
+.dur { color: #6e7781; font-size: 12px; }
+.params { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 12px; }
+.text { white-space: pre-wrap; margin: 4px 0; }
+pre { background: #f6f8fa; padding: 8px; overflow-x: auto; font-size: 12px; white-space: pre-wrap; }
+pre.result.error { background: #ffebe9; }
+pre.diff .add { background: #dafbe1; display: block; }
+pre.diff .del { background: #ffebe9; display: block; }
+.pending { color: #6e7781; font-style: italic; }
+.match > summary { background: #fff8c5; }
+</style>
+</head>
+<body>
+<header>
+<h1>Claude session report</h1>
+<input id="search" type="search" placeholder="Search">
+<span id="count"></span>
+<button type="button" id="expand">Expand all</button>
+<button type="button" id="collapse">Collapse all</button>
+</header>
+<main>
+{{range .}}
+<section class="session">
+<h2>{{.Title}}</h2>
+{{with .Init}}
+<table>
+<tr><th>Model</th><td>{{.Model}}</td></tr>
+<tr><th>Working directory</th><td>{{.Cwd}}</td></tr>
+<tr><th>Claude Code</th><td>{{.ClaudeCodeVersion}}</td></tr>
+</table>
+{{end}}
+{{with .Result}}
+<h3>Metrics</h3>
+<table>
+<tr><th>Status</th><td>{{if .IsError}}error{{else}}{{.Subtype}}{{end}}</td></tr>
+<tr><th>Duration</th><td>{{ms .DurationMs}} (API {{ms .DurationAPIMs}})</td></tr>
+<tr><th>Cost</th><td>{{cost .TotalCostUsd}}</td></tr>
+<tr><th>Turns</th><td>{{.NumTurns}}</td></tr>
+<tr><th>Tokens</th><td>input {{.Usage.InputTokens}} / output {{.Usage.OutputTokens}} / cache read {{.Usage.CacheReadInputTokens}} / cache write {{.Usage.CacheCreationInputTokens}}</td></tr>
+<tr><th>Permission denials</th><td>{{len .PermissionDenials}}</td></tr>
+</table>
+{{if .ModelUsage}}
+<table>
+<tr><th>Model</th><th>Input</th><th>Output</th><th>Cache read</th><th>Cache write</th><th>Cost</th></tr>
+{{range $model, $u := .ModelUsage}}<tr><td>{{$model}}</td><td>{{$u.InputTokens}}</td><td>{{$u.OutputTokens}}</td><td>{{$u.CacheReadInputTokens}}</td><td>{{$u.CacheCreationInputTokens}}</td><td>{{cost $u.CostUSD}}</td></tr>
+{{end}}</table>
+{{end}}
+{{end}}
+<h3>Steps</h3>
+<div class="tree">
+{{range .Nodes}}{{template "node" .}}{{end}}
+</div>
+{{with .Result}}
+<h3>Result</h3>
+<div class="text">{{.Result}}</div>
+{{end}}
+</section>
+{{end}}
+</main>
+<script>
+(function () {
+  var search = document.getElementById('search');
+  var count = document.getElementById('count');
+  search.addEventListener('input', function () {
+    var q = search.value.trim().toLowerCase();
+    document.querySelectorAll('.node').forEach(function (el) { el.classList.remove('match'); });
+    if (!q) { count.textContent = ''; return; }
+    var n = 0;
+    document.querySelectorAll('.node > summary, .node > .text, .node > pre').forEach(function (el) {
+      if (el.textContent.toLowerCase().indexOf(q) < 0) { return; }
+      var node = el.parentElement;
+      if (!node.classList.contains('match')) { n++; }
+      node.classList.add('match');
+      for (var p = node; p; p = p.parentElement && p.parentElement.closest('details')) { p.open = true; }
+    });
+    count.textContent = n + ' matches';
+  });
+  document.getElementById('expand').addEventListener('click', function () {
+    document.querySelectorAll('details').forEach(function (el) { el.open = true; });
+  });
+  document.getElementById('collapse').addEventListener('click', function () {
+    document.querySelectorAll('details').forEach(function (el) { el.open = false; });
+  });
+})();
+</script>
+</body>
+</html>
+{{define "node"}}{{if eq .Step.Kind "turn"}}<details class="node turn" open>
+<summary><span class="kind">turn</span> {{.Step.Name}} <span class="dur">{{duration .Step}}</span></summary>
+{{if .Step.Text}}<div class="text">{{.Step.Text}}</div>{{end}}
+{{range .Children}}{{template "node" .}}{{end}}</details>
+{{else}}<details class="node tool{{if .Step.IsError}} error{{end}}{{if .Children}} subagent{{end}}"{{if or .Step.IsError .Children}} open{{end}}>
+<summary><span class="tool-name">{{.Step.Name}}</span> <span class="params">{{params .Step}}</span> <span class="dur">{{duration .Step}}</span>{{if .Step.IsError}} <span class="badge">error</span>{{end}}</summary>
+{{with diff .Step}}<pre class="diff">{{range .}}<span class="{{.Op}}">{{prefix .Op}} {{.Text}}</span>{{end}}</pre>{{else}}<pre class="input">{{input .Step}}</pre>{{end}}
+{{range .Children}}{{template "node" .}}{{end}}{{if .Step.Done}}<pre class="result{{if .Step.IsError}} error{{end}}">{{.Step.Result}}</pre>{{else}}<p class="pending">(no result)</p>{{end}}
+</details>
+{{end}}{{end}}`))
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteHTMLReport(t *testing.T) {
+	tracker := trackLines(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, []string{
+		`{"type":"system","subtype":"init","cwd":"/work","session_id":"s1","model":"sonnet","claude_code_version":"2.0.21"}`,
+		`{"type":"assistant","message":{"id":"m1","model":"sonnet","content":[{"type":"text","text":"Fixing <main>"},{"type":"tool_use","id":"t1","name":"Edit","input":{"file_path":"a.go","old_string":"x := 1","new_string":"x := 2"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"edited"}]}}`,
+		`{"type":"assistant","message":{"id":"m2","model":"sonnet","content":[{"type":"tool_use","id":"task1","name":"Task","input":{"description":"explore"}}]}}`,
+		`{"type":"assistant","message":{"id":"s1","model":"haiku","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"rm -rf /"}}]},"parent_tool_use_id":"task1"}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t2","content":"permission denied","is_error":true}]},"parent_tool_use_id":"task1"}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"task1","content":"explored"}]}}`,
+		`{"type":"result","subtype":"success","result":"All done","duration_ms":7000,"duration_api_ms":5000,"total_cost_usd":0.05,"num_turns":3,"session_id":"s1","usage":{"input_tokens":10,"output_tokens":20},"modelUsage":{"haiku":{"inputTokens":5,"outputTokens":6,"costUSD":0.01}}}`,
+	})
+
+	var buf bytes.Buffer
+	s := &stream{config: &FilterConfig{Tracker: tracker}}
+	if err := writeHTMLReport(&buf, []*stream{s}); err != nil {
+		t.Fatalf("writeHTMLReport() error = %v", err)
+	}
+	got := buf.String()
+
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"Claude session s1",
+		"Fixing &lt;main&gt;",
+		`<span class="del">- x := 1</span>`,
+		`<span class="add">&#43; x := 2</span>`,
+		`class="node tool subagent"`,
+		`<span class="tool-name">Bash</span>`,
+		`class="node tool error" open`,
+		`<pre class="result error">permission denied</pre>`,
+		"7.0s (API 5.0s)",
+		"$0.0500",
+		"<td>haiku</td>",
+		"All done",
+		`id="search"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("HTML report does not contain %q", want)
+		}
+	}
+
+	// ネットワーク上のアセットを参照しない
+	for _, forbidden := range []string{"<link", "src=", "http://", "https://"} {
+		if strings.Contains(got, forbidden) {
+			t.Errorf("HTML report should be self-contained, found %q", forbidden)
+		}
+	}
+
+	// サブエージェントのターンは Task の下に入れ子になる
+	taskIndex := strings.Index(got, `<span class="tool-name">Task</span>`)
+	bashIndex := strings.Index(got, `<span class="tool-name">Bash</span>`)
+	if taskIndex < 0 || bashIndex < taskIndex {
+		t.Error("subagent steps should be nested under the Task call")
+	}
+}
+
+func TestEditDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		step Step
+		want []diffLine
+	}{
+		{
+			name: "edit",
+			step: Step{Name: "Edit", Input: []byte(`{"old_string":"a\nb","new_string":"c"}`)},
+			want: []diffLine{{"del", "a"}, {"del", "b"}, {"add", "c"}},
+		},
+		{
+			name: "multi edit",
+			step: Step{Name: "MultiEdit", Input: []byte(`{"edits":[{"old_string":"a","new_string":"b"},{"old_string":"c","new_string":""}]}`)},
+			want: []diffLine{{"del", "a"}, {"add", "b"}, {"del", "c"}},
+		},
+		{
+			name: "write",
+			step: Step{Name: "Write", Input: []byte(`{"content":"line1\nline2\n"}`)},
+			want: []diffLine{{"add", "line1"}, {"add", "line2"}},
+		},
+		{
+			name: "other tool",
+			step: Step{Name: "Bash", Input: []byte(`{"command":"ls"}`)},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := editDiff(&tt.step)
+			if len(got) != len(tt.want) {
+				t.Fatalf("editDiff() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("editDiff()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProcessInput_HTML(t *testing.T) {
+	input, err := os.ReadFile("testdata/permission_denied.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := NewFilterConfig()
+	config.Format = "html"
+	config.ShowStats = true
+
+	var output bytes.Buffer
+	if err := processInput(bytes.NewReader(input), &output, config); err != nil {
+		t.Fatalf("processInput() error = %v", err)
+	}
+
+	got := output.String()
+	if !strings.HasPrefix(got, "<!DOCTYPE html>") {
+		t.Errorf("HTML output should start with the doctype, got: %.80s", got)
+	}
+	if strings.Contains(got, "Stats:") {
+		t.Error("text reports should not be mixed into the HTML output")
+	}
+}
+
+func TestParseArgs_Report(t *testing.T) {
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	out := filepath.Join(t.TempDir(), "run.html")
+	os.Args = []string{"cmd", "report", "-o", out, "session.jsonl"}
+
+	got, err := parseArgs()
+	if err != nil {
+		t.Fatalf("parseArgs() error = %v", err)
+	}
+	if got.Command != "report" || got.Format != "html" || got.OutputPath != out {
+		t.Errorf("parseArgs() = Command %q, Format %q, OutputPath %q", got.Command, got.Format, got.OutputPath)
+	}
+	if len(got.Inputs) != 1 || got.Inputs[0].Path != "session.jsonl" {
+		t.Errorf("Inputs = %+v", got.Inputs)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+)
+
+// junitTestSuites は JUnit XML のルート要素
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite は1セッション分のテストスイート
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase はツール呼び出しまたは最終結果1件分のテストケース
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+// junitProblem は failure/error 要素
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Content string `xml:",chardata"`
+}
+
+// writeJUnit は各セッションを JUnit XML のテストスイートとして書き出す
+func writeJUnit(path string, streams []*stream) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create junit file: %w", err)
+	}
+	defer f.Close()
+
+	return encodeJUnit(f, streams)
+}
+
+// encodeJUnit は各セッションを JUnit XML として w に書き出す
+func encodeJUnit(w io.Writer, streams []*stream) error {
+	var root junitTestSuites
+	for _, s := range streams {
+		root.Suites = append(root.Suites, newJUnitTestSuite(s.config.Tracker))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// newJUnitTestSuite は Tracker の内容をテストスイートに変換
+// ツール呼び出しは testcase、エラーになった結果は failure、パーミッション拒否は error になる
+func newJUnitTestSuite(t *Tracker) junitTestSuite {
+	sessionID := t.SessionID()
+	suite := junitTestSuite{Name: "claude"}
+	if sessionID != "" {
+		suite.Name += " " + sessionID
+	}
+	className := "claude." + sessionLabel("", sessionID, "session")
+
+	denied := make(map[string]bool)
+	if t.Result != nil {
+		for _, denial := range t.Result.PermissionDenials {
+			denied[denial.ToolUseID] = true
+		}
+	}
+
+	sequence := 0
+	for _, step := range t.Steps {
+		if step.Kind != "tool" {
+			continue
+		}
+		sequence++
+
+		name := fmt.Sprintf("%03d %s", sequence, step.Name)
+		if _, param, ok := mainParam(step.Name, step.Input); ok {
+			name += " " + firstLine(param)
+		}
+		tc := junitTestCase{
+			Name:      name,
+			ClassName: className,
+			Time:      junitSeconds(step.Duration().Milliseconds()),
+		}
+
+		switch {
+		case denied[step.ID]:
+			tc.Error = &junitProblem{Message: "permission denied", Type: "PermissionDenied", Content: step.Result}
+			suite.Errors++
+		case step.IsError:
+			tc.Failure = &junitProblem{Message: firstLine(step.Result), Type: "ToolError", Content: step.Result}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	// result メッセージがスイート全体の成否になる
+	result := junitTestCase{Name: "result", ClassName: className, Time: junitSeconds(0)}
+	if r := t.Result; r != nil {
+		suite.Time = junitSeconds(int64(r.DurationMs))
+		result.Time = suite.Time
+		if r.IsError {
+			result.Failure = &junitProblem{Message: r.Subtype, Type: "SessionError", Content: r.Result}
+			suite.Failures++
+		}
+	} else {
+		suite.Time = junitSeconds(t.End.Sub(t.Start).Milliseconds())
+		result.Error = &junitProblem{Message: "no result message", Type: "Incomplete"}
+		suite.Errors++
+	}
+	suite.Cases = append(suite.Cases, result)
+	suite.Tests = len(suite.Cases)
+
+	return suite
+}
+
+// junitSeconds はミリ秒を JUnit の time 属性 (秒) にフォーマット
+func junitSeconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000.0)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestNewJUnitTestSuite(t *testing.T) {
+	tracker := trackLines(t, []int{0, 1, 3, 4, 5, 6, 7}, []string{
+		`{"type":"system","subtype":"init","session_id":"session-1234567890","model":"sonnet"}`,
+		`{"type":"assistant","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"main.go"}},{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"go test"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"package main"}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t2","content":"FAIL\nexit 1","is_error":true}]}}`,
+		`{"type":"assistant","message":{"id":"m2","content":[{"type":"tool_use","id":"t3","name":"Write","input":{"file_path":"a.go","content":"x"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t3","content":"permission required","is_error":true}]}}`,
+		`{"type":"result","subtype":"error_max_turns","is_error":true,"result":"gave up","duration_ms":6500,"session_id":"session-1234567890","permission_denials":[{"tool_name":"Write","tool_use_id":"t3"}]}`,
+	})
+
+	suite := newJUnitTestSuite(tracker)
+
+	if suite.Name != "claude session-1234567890" || suite.Time != "6.500" {
+		t.Errorf("suite = name %q time %q", suite.Name, suite.Time)
+	}
+	if suite.Tests != 4 || suite.Failures != 2 || suite.Errors != 1 {
+		t.Errorf("suite counts = tests %d failures %d errors %d, want 4/2/1", suite.Tests, suite.Failures, suite.Errors)
+	}
+
+	tests := []struct {
+		name    string
+		time    string
+		failure string
+		errType string
+	}{
+		{name: "001 Read main.go", time: "2.000"},
+		{name: "002 Bash go test", time: "3.000", failure: "FAIL ..."},
+		{name: "003 Write a.go", time: "1.000", errType: "PermissionDenied"},
+		{name: "result", time: "6.500", failure: "error_max_turns"},
+	}
+	for i, tt := range tests {
+		tc := suite.Cases[i]
+		if tc.Name != tt.name || tc.Time != tt.time || tc.ClassName != "claude.session-" {
+			t.Errorf("case %d = %q %q %q, want %q %q", i, tc.Name, tc.Time, tc.ClassName, tt.name, tt.time)
+		}
+		if (tc.Failure != nil) != (tt.failure != "") || tc.Failure != nil && tc.Failure.Message != tt.failure {
+			t.Errorf("case %d failure = %+v, want %q", i, tc.Failure, tt.failure)
+		}
+		if (tc.Error != nil) != (tt.errType != "") || tc.Error != nil && tc.Error.Type != tt.errType {
+			t.Errorf("case %d error = %+v, want %q", i, tc.Error, tt.errType)
+		}
+	}
+}
+
+func TestNewJUnitTestSuite_NoResult(t *testing.T) {
+	tracker := trackLines(t, []int{0, 2}, []string{
+		`{"type":"system","subtype":"init","session_id":"s1"}`,
+		`{"type":"assistant","message":{"id":"m1","content":[{"type":"text","text":"working"}]}}`,
+	})
+
+	suite := newJUnitTestSuite(tracker)
+
+	if suite.Tests != 1 || suite.Errors != 1 || suite.Time != "2.000" {
+		t.Errorf("suite = tests %d errors %d time %q", suite.Tests, suite.Errors, suite.Time)
+	}
+	if e := suite.Cases[0].Error; e == nil || e.Type != "Incomplete" {
+		t.Errorf("result case error = %+v, want Incomplete", e)
+	}
+}
+
+func TestEncodeJUnit(t *testing.T) {
+	tracker := trackFile(t, "testdata/permission_denied.json")
+
+	var output bytes.Buffer
+	if err := encodeJUnit(&output, []*stream{{config: &FilterConfig{Tracker: tracker}}}); err != nil {
+		t.Fatalf("encodeJUnit() error = %v", err)
+	}
+
+	got := output.String()
+	if !strings.HasPrefix(got, xml.Header) {
+		t.Errorf("JUnit output should start with the XML header")
+	}
+
+	// CI のレポーターが読めるよう、再度パースできることを確認
+	var parsed junitTestSuites
+	if err := xml.Unmarshal(output.Bytes(), &parsed); err != nil {
+		t.Fatalf("JUnit output is not valid XML: %v", err)
+	}
+	if len(parsed.Suites) != 1 || parsed.Suites[0].Errors != 1 || parsed.Suites[0].Time != "7.708" {
+		t.Errorf("parsed suites = %+v", parsed.Suites)
+	}
+}
@@ -1,14 +1,27 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strings"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "stats" {
+		if err := runStatsCommand(os.Args[2:], os.Stdout); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	config, err := parseArgs()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -23,54 +36,40 @@ func main() {
 
 // run はメイン処理を実行
 func run(config *FilterConfig) error {
-	return processInput(os.Stdin, os.Stdout, config)
-}
-
-// processInput は入力を処理して出力
-func processInput(input io.Reader, output io.Writer, config *FilterConfig) error {
-	scanner := bufio.NewScanner(input)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// 空行はスキップ
-		if line == "" {
-			continue
-		}
-
-		// メッセージタイプを判定
-		msgType, err := parseMessageType(line)
+	var output io.Writer = os.Stdout
+	if config.OutputPath != "" {
+		f, err := os.Create(config.OutputPath)
 		if err != nil {
-			// JSONパースエラーは警告を出してスキップ
-			fmt.Fprintf(os.Stderr, "Warning: failed to parse JSON: %v\n", err)
-			continue
-		}
-
-		// フィルタリング
-		if !shouldDisplay(msgType, config) {
-			continue
-		}
-
-		// フォーマット
-		formatted, err := formatMessage(msgType, []byte(line), config)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to format message: %v\n", err)
-			continue
+			return fmt.Errorf("failed to create output file: %w", err)
 		}
+		defer f.Close()
+		output = f
+	}
 
-		// 出力
-		if formatted != "" {
-			fmt.Fprint(output, formatted)
-		}
+	if config.ColorMode == "auto" {
+		config.UseColor = detectColor(capabilitiesOf(output), os.Getenv)
+	}
+	if config.UseColor {
+		config.ColorDepth = detectColorDepth(os.Getenv)
+	}
+	if config.Width < 0 {
+		config.Width = detectWidth(capabilitiesOf(output), os.Getenv)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("failed to read input: %w", err)
+	if len(config.Inputs) > 0 {
+		return processInputs(config.Inputs, output, config)
 	}
+	return processInput(os.Stdin, output, config)
+}
 
-	return nil
+// processInput は入力を処理して出力
+func processInput(input io.Reader, output io.Writer, config *FilterConfig) error {
+	return processInputs([]InputSource{{Reader: input}}, output, config)
 }
 
+// validFormats は --format で指定できる出力形式
+var validFormats = []string{"text", "json", "compact", "markdown", "html", "csv", "mermaid", "dot", "stream-json"}
+
 // parseArgs はコマンドライン引数をパース
 func parseArgs() (*FilterConfig, error) {
 	config := NewFilterConfig()
@@ -91,16 +90,71 @@ func parseArgs() (*FilterConfig, error) {
 		showUsage  = flag.Bool("show-usage", false, "Always show token usage")
 		showTiming = flag.Bool("show-timing", false, "Always show timing information")
 
-		noColor = flag.Bool("no-color", false, "Disable color output")
-		color   = flag.Bool("color", false, "Force enable color output")
-
-		format = flag.String("format", "text", "Output format (text|json|compact)")
+		showLatency  = flag.Bool("show-latency", false, "Show how long each tool call took")
+		showTimeline = flag.Bool("timeline", false, "Show a timeline of the run, slowest steps first")
+
+		showStats    = flag.Bool("stats", false, "Show an end-of-run stats summary")
+		statsJSON    = flag.String("stats-json", "", "Write end-of-run stats as JSON to FILE")
+		metricsCSV   = flag.String("metrics-csv", "", "Write per-session metrics as CSV to FILE")
+		junit        = flag.String("junit", "", "Write the session as a JUnit XML test suite to FILE")
+		otelFile     = flag.String("otel-file", "", "Write the session as an OTLP/JSON trace to FILE")
+		otelEndpoint = flag.String("otel-endpoint", "", "Send the session trace to an OTLP/HTTP endpoint")
+		traceEvents  = flag.String("trace-events", "", "Write a Chrome/Perfetto trace-event timeline to FILE")
+		promTextfile = flag.String("prom-textfile", "", "Write Prometheus metrics for the node_exporter textfile collector to PATH")
+		promPush     = flag.String("prom-push", "", "Push Prometheus metrics to a Pushgateway URL")
+
+		cast       = flag.String("cast", "", "Record the rendered output as an asciinema v2 cast to FILE")
+		castWidth  = flag.Int("cast-width", config.CastWidth, "Terminal width of the cast")
+		castHeight = flag.Int("cast-height", config.CastHeight, "Terminal height of the cast")
+		castIdle   = flag.Float64("cast-idle", 0, "Cap idle gaps in the cast to SECONDS")
+
+		githubActions = flag.Bool("github-actions", os.Getenv("GITHUB_ACTIONS") == "true", "Emit GitHub Actions workflow commands")
+
+		noColor      = flag.Bool("no-color", false, "Disable color output (same as --color=never)")
+		width        = flag.Int("width", -1, "Wrap output at N display cells (0 disables wrapping)")
+		maxLines     = flag.Int("max-lines", 0, "Show the first N lines of each tool result (0 shows all lines)")
+		tailLines    = flag.Int("tail-lines", 0, "Also show the last N lines of each tool result")
+		maxLineWidth = flag.Int("max-line-width", -1, "Cut tool result lines longer than N display cells (0 disables)")
+		noRedact     = flag.Bool("no-redact", false, "Do not redact secrets (API keys, tokens, private keys, passwords)")
+		absolutePath = flag.Bool("absolute-paths", false, "Show absolute paths instead of paths relative to the session cwd or ~")
+		anonymize    = flag.Bool("anonymize", false, "Replace home directories, usernames, session IDs and UUIDs with placeholders")
+		dropResults  = flag.Bool("drop-results", false, "Omit tool result bodies (implies --anonymize)")
+		sanitize     = flag.String("sanitize", "", "Control characters in tool input and output: escape, strip or off [default: escape]")
+		theme        = flag.String("theme", "", "Color theme (dark|light|solarized|mono or a theme from the config file)")
+		configPath   = flag.String("config", "", "Read settings from FILE instead of the default config file")
+
+		format = flag.String("format", "text", "Output format (text|json|compact|markdown|html|csv|mermaid|dot|stream-json)")
+		out    = flag.String("o", "", "Write output to FILE instead of stdout")
+
+		record = flag.String("record", "", "Record input lines with arrival times to FILE")
+		speed  = flag.String("speed", "1x", "Replay speed (e.g. 4x, 0.5x, instant)")
+
+		runCommands stringList
+		toolLines   stringList
+		redactRules stringList
 
 		help = flag.Bool("help", false, "Show help message")
 		h    = flag.Bool("h", false, "Show help message (short)")
 	)
 
-	flag.Parse()
+	flag.Var(&runCommands, "run", "Run a command and read its stdout as an input (repeatable)")
+	flag.Var(&redactRules, "redact-pattern", "Also redact matches of a regular expression given as NAME=REGEX (repeatable)")
+	flag.Var(&toolLines, "tool-lines", "Lines to show for one tool's results as TOOL=HEAD[:TAIL] (repeatable)")
+	flag.Var(colorFlag{mode: &config.ColorMode}, "color", "Color output: auto, always or never (--color alone means always)")
+
+	args := os.Args[1:]
+	if len(args) > 0 && (args[0] == "replay" || args[0] == "report" || args[0] == "anonymize") {
+		config.Command = args[0]
+		args = args[1:]
+	}
+
+	if err := checkColorArgs(args); err != nil {
+		return nil, err
+	}
+	args, err := parseInterspersed(flag.CommandLine, args)
+	if err != nil {
+		return nil, err
+	}
 
 	// ヘルプ表示
 	if *help || *h {
@@ -155,32 +209,283 @@ func parseArgs() (*FilterConfig, error) {
 	if *showTiming {
 		config.ShowTiming = true
 	}
+	config.ShowLatency = *showLatency
+	config.ShowTimeline = *showTimeline
+	config.ShowStats = *showStats
+	config.StatsJSONPath = *statsJSON
+	config.MetricsCSVPath = *metricsCSV
+	config.JUnitPath = *junit
+	config.OTLPFile = *otelFile
+	config.OTLPEndpoint = *otelEndpoint
+	config.TraceEventsPath = *traceEvents
+	config.PromTextfile = *promTextfile
+	config.PromPushURL = *promPush
+	config.CastPath = *cast
+	config.CastWidth = *castWidth
+	config.CastHeight = *castHeight
+	config.CastIdleLimit = *castIdle
+	if config.CastWidth <= 0 || config.CastHeight <= 0 {
+		return nil, fmt.Errorf("invalid cast size: %dx%d", config.CastWidth, config.CastHeight)
+	}
+	config.GitHubActions = *githubActions
 
-	// カラー設定
+	// カラー設定 (auto は出力先が決まってから run で判定する)
 	if *noColor {
-		config.UseColor = false
-	} else if *color {
+		config.ColorMode = "never"
+	}
+	switch config.ColorMode {
+	case "always":
 		config.UseColor = true
+	case "never":
+		config.UseColor = false
+	}
+
+	// 表示幅 (負の場合は run で出力先から判定する)
+	config.Width = *width
+	config.MaxLineWidth = *maxLineWidth
+
+	// 設定ファイルとテーマ
+	settings, err := loadConfig(*configPath)
+	if err != nil {
+		return nil, err
+	}
+	config.Theme, err = settings.resolveTheme(*theme)
+	if err != nil {
+		return nil, err
+	}
+
+	// 制御文字の扱い (フラグは設定ファイルより優先)
+	if settings.Sanitize != "" {
+		config.Sanitize = settings.Sanitize
+	}
+	if *sanitize != "" {
+		config.Sanitize = *sanitize
+	}
+	if !slices.Contains(validSanitizeModes, config.Sanitize) {
+		return nil, fmt.Errorf("invalid sanitize mode: %s (must be one of %s)", config.Sanitize, strings.Join(validSanitizeModes, ", "))
+	}
+
+	// 秘密情報の伏せ字 (設定ファイルのパターンにフラグのパターンを追加する)
+	if !*noRedact && (settings.Redact == nil || *settings.Redact) {
+		patterns := make(map[string]string)
+		for name, pattern := range settings.RedactPatterns {
+			patterns[name] = pattern
+		}
+		for _, spec := range redactRules {
+			name, pattern, ok := strings.Cut(spec, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid --redact-pattern: %s (must be NAME=REGEX)", spec)
+			}
+			patterns[name] = pattern
+		}
+		if config.Redactor, err = newRedactor(patterns); err != nil {
+			return nil, err
+		}
+	}
+
+	// パスの表示 (作業ディレクトリは入力の init メッセージから得る)
+	config.AbsolutePaths = *absolutePath || settings.AbsolutePaths
+	if home, err := os.UserHomeDir(); err == nil {
+		config.HomeDir = home
+	}
+
+	// 共有用の匿名化 (実行したユーザー自身のホームディレクトリとユーザー名は入力に現れる前から対象にする)
+	if *anonymize || *dropResults || config.Command == "anonymize" {
+		config.Anonymizer = newAnonymizer(*dropResults)
+		if home, err := os.UserHomeDir(); err == nil {
+			config.Anonymizer.addHome(home)
+		}
+		config.Anonymizer.addUser(os.Getenv("USER"))
+	}
+
+	// ツール結果を表示する行数 (フラグは設定ファイルより優先)
+	if err := settings.applyLimits(config); err != nil {
+		return nil, err
+	}
+	visited := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { visited[f.Name] = true })
+	if visited["max-lines"] {
+		if config.MaxLines, err = maxLinesValue(*maxLines); err != nil {
+			return nil, fmt.Errorf("invalid --max-lines: %w", err)
+		}
+	}
+	if visited["tail-lines"] {
+		if *tailLines < 0 {
+			return nil, fmt.Errorf("invalid --tail-lines: must not be negative")
+		}
+		config.TailLines = *tailLines
+	}
+	for _, spec := range toolLines {
+		tool, limit, err := parseToolLines(spec)
+		if err != nil {
+			return nil, err
+		}
+		if config.ToolLines == nil {
+			config.ToolLines = make(map[string]lineLimit)
+		}
+		config.ToolLines[tool] = limit
 	}
 
 	// フォーマット
 	config.Format = *format
-	if config.Format != "text" && config.Format != "json" && config.Format != "compact" {
-		return nil, fmt.Errorf("invalid format: %s (must be text, json, or compact)", config.Format)
+	if config.Command == "report" {
+		config.Format = "html"
+	}
+	if config.Command == "anonymize" && !visited["format"] {
+		config.Format = "stream-json"
+	}
+	config.OutputPath = *out
+	if !slices.Contains(validFormats, config.Format) {
+		return nil, fmt.Errorf("invalid format: %s (must be one of %s)", config.Format, strings.Join(validFormats, ", "))
+	}
+
+	// 入力元
+	for _, arg := range args {
+		name, path := parseInputSpec(arg)
+		config.Inputs = append(config.Inputs, InputSource{Name: name, Path: path})
+	}
+	for _, command := range runCommands {
+		name, cmd := parseInputSpec(command)
+		config.Inputs = append(config.Inputs, InputSource{Name: name, Command: cmd})
+	}
+
+	// 記録と再生
+	config.RecordPath = *record
+	if config.Command == "replay" {
+		if len(args) != 1 || len(runCommands) > 0 {
+			return nil, fmt.Errorf("replay requires exactly one recorded FILE")
+		}
+		config.ReplaySpeed, err = parseSpeed(*speed)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return config, nil
 }
 
+// stringList は繰り返し指定可能な文字列フラグ
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseInterspersed はフラグと位置引数が混在した引数列をパースし、位置引数を返す
+// "--" の後の引数は - で始まっていてもすべて位置引数とする
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" && !(n > 1 && takesValue(fs, args[n-2])) {
+			return append(positional, rest...), nil
+		}
+		args = rest
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// takesValue は arg が次の引数を値として受け取るフラグ (-o FILE など) かどうかを判定
+func takesValue(fs *flag.FlagSet, arg string) bool {
+	if !strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") {
+		return false
+	}
+	f := fs.Lookup(strings.TrimLeft(arg, "-"))
+	if f == nil {
+		return false
+	}
+	if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		return false
+	}
+	return true
+}
+
 // printHelp はヘルプメッセージを表示
 func printHelp() {
-	fmt.Fprintf(os.Stderr, `Usage: ccfilter [options]
+	fmt.Fprintf(os.Stderr, `Usage: ccfilter [options] [[NAME=]FILE ...]
+       ccfilter replay [options] FILE
+       ccfilter report [-o FILE] [FILE]
+       ccfilter stats [--format=table|csv|json] DIR|FILE ...
+       ccfilter anonymize [--drop-results] [-o FILE] [FILE ...]
 
 ccfilter filters Claude CLI stream-json output for human readability.
 
 Usage:
   claude -p --verbose --output-format=stream-json <prompt> | ccfilter [options]
 
+Inputs:
+  FILE ...          Read stream-json from files or FIFOs instead of stdin
+  --run=CMD         Run CMD and read its stdout (repeatable)
+  (prefix with NAME= to label a session; with several inputs, events are
+   interleaved as they arrive and a combined cost summary is printed)
+
+Record and Replay:
+  --record=FILE     Record input lines with their arrival times to FILE
+  replay FILE       Re-render a recorded FILE with its original pacing
+  --speed=SPEED     Replay speed (e.g. 4x, 0.5x, instant) [default: 1x]
+
+Asciinema:
+  --cast=FILE       Record exactly what is printed (including colors) as an
+                    asciinema v2 cast, timed by arrival or recorded times
+  --cast-width=N    Terminal width of the cast [default: 80]
+  --cast-height=N   Terminal height of the cast [default: 24]
+  --cast-idle=SEC   Cap idle gaps between outputs to SEC seconds
+
+Reports:
+  report [FILE]     Write a self-contained HTML report (same as --format=html)
+
+Sharing:
+  anonymize [FILE]  Write an anonymized copy of the session as stream-json
+                    (same as --anonymize --format=stream-json; use --format
+                    to write rendered text instead)
+  --anonymize       Replace usernames in home directory paths (and values that
+                    are just a username) with /home/user1, user1, ..., and
+                    session_id and uuid values with consistent placeholder UUIDs
+  --drop-results    Replace tool result bodies with "[result omitted: N bytes]"
+                    (implies --anonymize)
+
+Aggregate Stats:
+  stats DIR|FILE    Aggregate cost, duration, turns, tokens and tool usage
+                    across many session logs (see: ccfilter stats --help)
+
+OpenTelemetry:
+  --otel-file=FILE  Write the session as an OTLP/JSON trace to FILE
+  --otel-endpoint=URL
+                    Send the trace to an OTLP/HTTP collector
+                    (e.g. http://localhost:4318; /v1/traces is used if no path)
+                    The session is the root span, turns and Task calls are
+                    child spans, and tool calls are leaf spans
+  --trace-events=FILE
+                    Write a Chrome Trace Event timeline to FILE (open it in
+                    ui.perfetto.dev; one track per agent and subagent)
+
+Prometheus:
+  --prom-textfile=PATH
+                    Write metrics for the node_exporter textfile collector
+                    (tool calls, tokens and cost by model as gauges, since
+                    each run replaces the previous values, and a histogram
+                    of session durations)
+  --prom-push=URL   Push the same metrics to a Pushgateway
+                    (/metrics/job/ccfilter is used if no path)
+
+GitHub Actions:
+  --github-actions  Wrap each turn in ::group::, annotate failed tool results
+                    and permission denials with ::error::, and append the
+                    result to $GITHUB_STEP_SUMMARY (text format only)
+                    [default: true when GITHUB_ACTIONS=true]
+
 Message Type Filters:
   --system          Show system messages
   --assistant       Show only assistant messages
@@ -190,20 +495,80 @@ Message Type Filters:
 
 Information Level:
   --minimal, -m     Show minimal information
-  --verbose, -v     Show verbose information
+  --verbose, -v     Show verbose information (including Write contents, Edit
+                    diffs and thinking blocks)
   (default is standard level)
 
 Additional Information:
   --show-cost       Always show cost information
   --show-usage      Always show token usage
   --show-timing     Always show timing information
+  --show-latency    Show how long each tool call took on its result line
+  --timeline        Show a timeline of turns and tool calls, slowest first
+                    (text format only)
+  --stats           Show an end-of-run stats summary (tools, errors, tokens, files)
+                    (text format only; use --stats-json with other formats)
+  --stats-json=FILE Write end-of-run stats as JSON to FILE
+  --metrics-csv=FILE
+                    Write one CSV row per session (duration, cost, turns,
+                    tokens by kind, model) to FILE
+  --junit=FILE      Write a JUnit XML test suite to FILE (tool calls are test
+                    cases; failed results, permission denials and an
+                    unsuccessful session are reported as failures/errors)
 
 Output Format:
-  --format=FORMAT   Output format (text|json|compact) [default: text]
-  --color           Force enable color output
-  --no-color        Disable color output
+  --format=FORMAT   Output format
+                    (text|json|compact|markdown|html|csv|mermaid|dot|stream-json)
+                    [default: text]
+                    json: the displayed messages, one JSON object per line
+                    markdown: shareable transcript for PRs and incident notes
+                    html: single static HTML report written when input ends
+                    csv: one row per tool call, written when input ends
+                    mermaid: sequence diagram of tool calls and results
+                    dot: Graphviz tree of subagents and tool calls
+                    stream-json: every input line after redaction and
+                    anonymization, readable by ccfilter again
+  -o FILE           Write output to FILE instead of stdout
+  --color=WHEN      Color output: auto, always or never [default: auto]
+                    auto enables color only when writing to a terminal,
+                    honoring NO_COLOR, FORCE_COLOR and TERM=dumb
+                    (--color alone means always; write the value with =)
+  --no-color        Disable color output (same as --color=never)
+  --width=N         Wrap output at N display cells, counting East Asian wide
+                    characters as two [default: terminal width or $COLUMNS;
+                    no wrapping when not writing to a terminal; 0 disables]
+  --max-lines=N     Show the first N lines of each tool result
+                    [default: 5, all with --verbose; 0 shows all lines]
+  --tail-lines=N    Also show the last N lines, with "… N lines omitted …"
+                    in between (test and compiler output end with the summary)
+  --tool-lines=TOOL=HEAD[:TAIL]
+                    Override the lines for one tool, e.g. Bash=20:10 or
+                    Read=3 (repeatable)
+  --max-line-width=N
+                    Cut tool result lines longer than N display cells with
+                    "… (N more chars)" [default: 500, unlimited with --verbose;
+                    0 disables]
+  --sanitize=MODE   How to handle terminal control characters and ANSI/OSC
+                    escape sequences in tool input, tool output and model
+                    text: escape (show them as \x1b), strip or off
+                    [default: escape]
+  --no-redact       Do not replace secrets with placeholders such as
+                    [REDACTED:github-token#1] (AWS keys, GitHub tokens,
+                    Anthropic API keys, JWTs, private keys, passwords)
+  --redact-pattern=NAME=REGEX
+                    Also redact matches of REGEX; a (?P<secret>...) group
+                    limits the redaction to that part (repeatable)
+  --absolute-paths  Show file_path parameters and paths in Bash commands and
+                    tool results as they are, instead of relative to the
+                    session cwd (from the init message) or starting with ~
+  --theme=NAME      Color theme: dark, light, solarized, mono or a theme
+                    defined in the config file [default: dark]
+                    Colors are reduced to what the terminal supports
+                    (COLORTERM=truecolor, TERM=*-256color, FORCE_COLOR=2/3)
 
 Other:
+  --config=FILE     Read settings from FILE
+                    [default: $XDG_CONFIG_HOME/ccfilter/config.json]
   --help, -h        Show this help message
 
 Examples:
@@ -218,5 +583,19 @@ Examples:
 
   # Verbose with cost and timing
   claude -p --verbose --output-format=stream-json "hello" | ccfilter --verbose --show-cost --show-timing
+
+  # Record a session, then replay it at 4x speed
+  claude -p --verbose --output-format=stream-json "hello" | ccfilter --record=session.jsonl
+  ccfilter replay session.jsonl --speed=4x
+
+  # Attach a session report to a ticket
+  claude -p --verbose --output-format=stream-json "fix bug" | ccfilter report -o run.html
+
+  # Share a session with another team without paths, names or tool output
+  ccfilter anonymize --drop-results session.jsonl -o shared.jsonl
+
+  # Watch two agents running in parallel
+  ccfilter --run='api=claude -p --verbose --output-format=stream-json "task A"' \
+           --run='web=claude -p --verbose --output-format=stream-json "task B"'
 `)
 }
@@ -3,7 +3,9 @@ package main
 import (
 	"bytes"
 	"flag"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -43,6 +45,22 @@ func TestProcessInput_Integration(t *testing.T) {
 			wantOutput: []string{"Searching", "→ Glob", "← main.go"},
 			wantErr:    false,
 		},
+		{
+			name: "timeline after result",
+			input: `{"type":"assistant","message":{"id":"m1","model":"sonnet","content":[{"type":"tool_use","id":"t1","name":"Glob","input":{"pattern":"*.go"}}]}}
+{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"main.go"}]}}
+{"type":"result","subtype":"success","result":"Done","duration_ms":1000,"total_cost_usd":0.01,"num_turns":1}`,
+			config: FilterConfig{
+				ShowTools:    true,
+				ShowResult:   true,
+				ShowLatency:  true,
+				ShowTimeline: true,
+				InfoLevel:    "standard",
+				UseColor:     false,
+			},
+			wantOutput: []string{"← (0.0s) main.go", "Timeline (slowest first):", "turn sonnet", `Glob: pattern="*.go"`},
+			wantErr:    false,
+		},
 		{
 			name: "filtering - only result",
 			input: `{"type":"assistant","message":{"content":[{"type":"text","text":"Hello"}]}}
@@ -296,3 +314,319 @@ func TestParseArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestParseInterspersed(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantOut string
+		wantV   bool
+	}{
+		{name: "flags between files", args: []string{"a.jsonl", "-v", "b.jsonl", "-o", "out.txt"}, want: []string{"a.jsonl", "b.jsonl"}, wantOut: "out.txt", wantV: true},
+		{name: "terminator", args: []string{"-v", "--", "-dash.jsonl", "-o"}, want: []string{"-dash.jsonl", "-o"}, wantV: true},
+		{name: "terminator after a file", args: []string{"a.jsonl", "--", "-v"}, want: []string{"a.jsonl", "-v"}},
+		{name: "terminator as a flag value", args: []string{"-o", "--", "a.jsonl", "-v"}, want: []string{"a.jsonl"}, wantOut: "--", wantV: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			out := fs.String("o", "", "")
+			v := fs.Bool("v", false, "")
+
+			got, err := parseInterspersed(fs, tt.args)
+			if err != nil {
+				t.Fatalf("parseInterspersed() error = %v", err)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("positional = %q, want %q", got, tt.want)
+			}
+			if *out != tt.wantOut || *v != tt.wantV {
+				t.Errorf("-o = %q, -v = %v, want %q, %v", *out, *v, tt.wantOut, tt.wantV)
+			}
+		})
+	}
+}
+
+func TestParseArgs_Inputs(t *testing.T) {
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	os.Args = []string{"cmd", "a.jsonl", "--no-color", "web=b.jsonl", "--run", "api=claude -p hi"}
+
+	got, err := parseArgs()
+	if err != nil {
+		t.Fatalf("parseArgs() error = %v", err)
+	}
+
+	want := []InputSource{
+		{Path: "a.jsonl"},
+		{Name: "web", Path: "b.jsonl"},
+		{Name: "api", Command: "claude -p hi"},
+	}
+	if len(got.Inputs) != len(want) {
+		t.Fatalf("Inputs = %+v, want %+v", got.Inputs, want)
+	}
+	for i := range want {
+		if got.Inputs[i] != want[i] {
+			t.Errorf("Inputs[%d] = %+v, want %+v", i, got.Inputs[i], want[i])
+		}
+	}
+	if got.UseColor {
+		t.Error("UseColor should be false when --no-color follows a positional argument")
+	}
+}
+
+func TestParseArgs_Replay(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantSpeed float64
+		wantErr   bool
+	}{
+		{name: "default speed", args: []string{"replay", "session.jsonl"}, wantSpeed: 1},
+		{name: "speed after file", args: []string{"replay", "session.jsonl", "--speed", "4x"}, wantSpeed: 4},
+		{name: "instant", args: []string{"replay", "--speed=instant", "session.jsonl"}, wantSpeed: 0},
+		{name: "missing file", args: []string{"replay"}, wantErr: true},
+		{name: "invalid speed", args: []string{"replay", "session.jsonl", "--speed=fast"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+			os.Args = append([]string{"cmd"}, tt.args...)
+
+			got, err := parseArgs()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Command != "replay" {
+				t.Errorf("Command = %q, want replay", got.Command)
+			}
+			if got.ReplaySpeed != tt.wantSpeed {
+				t.Errorf("ReplaySpeed = %v, want %v", got.ReplaySpeed, tt.wantSpeed)
+			}
+			if len(got.Inputs) != 1 || got.Inputs[0].Path != "session.jsonl" {
+				t.Errorf("Inputs = %+v, want session.jsonl", got.Inputs)
+			}
+		})
+	}
+}
+
+func TestParseArgs_Color(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantMode     string
+		wantUseColor bool
+		wantErr      bool
+	}{
+		{name: "default", args: []string{}, wantMode: "auto", wantUseColor: true},
+		{name: "bare --color", args: []string{"--color"}, wantMode: "always", wantUseColor: true},
+		{name: "always", args: []string{"--color=always"}, wantMode: "always", wantUseColor: true},
+		{name: "never", args: []string{"--color=never"}, wantMode: "never", wantUseColor: false},
+		{name: "auto", args: []string{"--color=auto"}, wantMode: "auto", wantUseColor: true},
+		{name: "no-color", args: []string{"--no-color"}, wantMode: "never", wantUseColor: false},
+		{name: "no-color wins", args: []string{"--color=always", "--no-color"}, wantMode: "never", wantUseColor: false},
+		{name: "invalid", args: []string{"--color=sometimes"}, wantErr: true},
+		{name: "space separated always", args: []string{"--color", "always"}, wantErr: true},
+		{name: "space separated never", args: []string{"session.jsonl", "--color", "never"}, wantErr: true},
+		{name: "bare --color before a file", args: []string{"--color", "session.jsonl"}, wantMode: "always", wantUseColor: true},
+		{name: "file named always after --", args: []string{"--color", "--", "always"}, wantMode: "always", wantUseColor: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+			flag.CommandLine.SetOutput(io.Discard)
+			os.Args = append([]string{"cmd"}, tt.args...)
+
+			got, err := parseArgs()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.ColorMode != tt.wantMode {
+				t.Errorf("ColorMode = %q, want %q", got.ColorMode, tt.wantMode)
+			}
+			if got.UseColor != tt.wantUseColor {
+				t.Errorf("UseColor = %v, want %v", got.UseColor, tt.wantUseColor)
+			}
+		})
+	}
+}
+
+func TestParseArgs_Lines(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	writeFile(t, configPath, `{"max_lines": 10, "tail_lines": 3, "tool_lines": {"Read": {"max_lines": 2}}}`)
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantMax   int
+		wantTail  int
+		wantTools map[string]lineLimit
+		wantErr   bool
+	}{
+		{name: "default", args: []string{}, wantMax: 0, wantTail: 0},
+		{name: "flags", args: []string{"--max-lines", "8", "--tail-lines", "4"}, wantMax: 8, wantTail: 4},
+		{name: "all lines", args: []string{"--max-lines=0"}, wantMax: -1},
+		{name: "tool lines", args: []string{"--tool-lines", "Bash=20:10", "--tool-lines", "Read=3"}, wantTools: map[string]lineLimit{"Bash": {Head: 20, Tail: 10}, "Read": {Head: 3}}},
+		{name: "config", args: []string{"--config", configPath}, wantMax: 10, wantTail: 3, wantTools: map[string]lineLimit{"Read": {Head: 2}}},
+		{name: "flags override config", args: []string{"--config", configPath, "--max-lines", "4", "--tool-lines", "Read=5"}, wantMax: 4, wantTail: 3, wantTools: map[string]lineLimit{"Read": {Head: 5}}},
+		{name: "negative", args: []string{"--max-lines", "-2"}, wantErr: true},
+		{name: "bad tool lines", args: []string{"--tool-lines", "Bash"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+			flag.CommandLine.SetOutput(io.Discard)
+			os.Args = append([]string{"cmd"}, tt.args...)
+
+			got, err := parseArgs()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.MaxLines != tt.wantMax || got.TailLines != tt.wantTail {
+				t.Errorf("MaxLines, TailLines = %d, %d, want %d, %d", got.MaxLines, got.TailLines, tt.wantMax, tt.wantTail)
+			}
+			if len(got.ToolLines) != len(tt.wantTools) {
+				t.Fatalf("ToolLines = %+v, want %+v", got.ToolLines, tt.wantTools)
+			}
+			for tool, limit := range tt.wantTools {
+				if got.ToolLines[tool] != limit {
+					t.Errorf("ToolLines[%s] = %+v, want %+v", tool, got.ToolLines[tool], limit)
+				}
+			}
+		})
+	}
+}
+
+func TestParseArgs_Redact(t *testing.T) {
+	dir := t.TempDir()
+	disabled := filepath.Join(dir, "disabled.json")
+	writeFile(t, disabled, `{"redact": false}`)
+	patterns := filepath.Join(dir, "patterns.json")
+	writeFile(t, patterns, `{"redact_patterns": {"internal-token": "itk_[a-z0-9]{8}"}}`)
+
+	tests := []struct {
+		name     string
+		args     []string
+		input    string
+		want     string
+		disabled bool
+		wantErr  bool
+	}{
+		{name: "enabled by default", args: []string{}, input: "password=hunter2", want: "password=[REDACTED:password#1]"},
+		{name: "no-redact", args: []string{"--no-redact"}, disabled: true},
+		{name: "disabled in config", args: []string{"--config", disabled}, disabled: true},
+		{name: "config patterns", args: []string{"--config", patterns}, input: "itk_abcd1234", want: "[REDACTED:internal-token#1]"},
+		{name: "flag patterns", args: []string{"--redact-pattern", "ticket=TICKET-[0-9]+"}, input: "see TICKET-42", want: "see [REDACTED:ticket#1]"},
+		{name: "bad flag pattern", args: []string{"--redact-pattern", "ticket"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+			flag.CommandLine.SetOutput(io.Discard)
+			os.Args = append([]string{"cmd"}, tt.args...)
+
+			got, err := parseArgs()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tt.disabled {
+				if got.Redactor != nil {
+					t.Error("Redactor should be nil")
+				}
+				return
+			}
+			if got.Redactor == nil {
+				t.Fatal("Redactor should be set")
+			}
+			if redacted := got.Redactor.redact(tt.input); redacted != tt.want {
+				t.Errorf("redact() = %q, want %q", redacted, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseArgs_Anonymize(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantFormat  string
+		wantAnon    bool
+		wantDropped bool
+	}{
+		{name: "off by default", args: []string{}, wantFormat: "text"},
+		{name: "flag", args: []string{"--anonymize"}, wantFormat: "text", wantAnon: true},
+		{name: "drop-results implies anonymize", args: []string{"--drop-results", "--format", "markdown"}, wantFormat: "markdown", wantAnon: true, wantDropped: true},
+		{name: "subcommand writes stream-json", args: []string{"anonymize", "session.jsonl"}, wantFormat: "stream-json", wantAnon: true},
+		{name: "subcommand with format", args: []string{"anonymize", "--format=text", "--drop-results", "session.jsonl"}, wantFormat: "text", wantAnon: true, wantDropped: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+			flag.CommandLine.SetOutput(io.Discard)
+			os.Args = append([]string{"cmd"}, tt.args...)
+
+			got, err := parseArgs()
+			if err != nil {
+				t.Fatalf("parseArgs() error = %v", err)
+			}
+			if got.Format != tt.wantFormat {
+				t.Errorf("Format = %q, want %q", got.Format, tt.wantFormat)
+			}
+			if (got.Anonymizer != nil) != tt.wantAnon {
+				t.Fatalf("Anonymizer set = %v, want %v", got.Anonymizer != nil, tt.wantAnon)
+			}
+			if got.Anonymizer != nil && got.Anonymizer.dropResults != tt.wantDropped {
+				t.Errorf("dropResults = %v, want %v", got.Anonymizer.dropResults, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestParseArgs_AbsolutePaths(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	writeFile(t, config, `{"absolute_paths": true}`)
+
+	tests := []struct {
+		name string
+		args []string
+		want bool
+	}{
+		{name: "relative by default", args: []string{}, want: false},
+		{name: "flag", args: []string{"--absolute-paths"}, want: true},
+		{name: "config", args: []string{"--config", config}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+			flag.CommandLine.SetOutput(io.Discard)
+			os.Args = append([]string{"cmd"}, tt.args...)
+
+			got, err := parseArgs()
+			if err != nil {
+				t.Fatalf("parseArgs() error = %v", err)
+			}
+			if got.AbsolutePaths != tt.want {
+				t.Errorf("AbsolutePaths = %v, want %v", got.AbsolutePaths, tt.want)
+			}
+		})
+	}
+}
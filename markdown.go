@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// formatMarkdownMessage はメッセージを Markdown の transcript 形式にフォーマット
+func formatMarkdownMessage(msgType string, data []byte, config *FilterConfig) (string, error) {
+	switch msgType {
+	case "system":
+		return formatMarkdownSystem(data)
+	case "assistant":
+		return formatMarkdownAssistant(data, config)
+	case "user":
+		return formatMarkdownUser(data, config)
+	case "result":
+		return formatMarkdownResult(data, config)
+	default:
+		return "", nil
+	}
+}
+
+// formatMarkdownSystem は init メッセージをセッションのヘッダーにする
+func formatMarkdownSystem(data []byte) (string, error) {
+	var msg SystemMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return "", err
+	}
+	if msg.Subtype != "init" {
+		return "", nil
+	}
+
+	var output strings.Builder
+	output.WriteString("# Claude session\n\n")
+	output.WriteString("| | |\n|---|---|\n")
+	rows := []metricField{
+		{"Session", msg.SessionID},
+		{"Model", msg.Model},
+		{"Working directory", msg.Cwd},
+		{"Claude Code", msg.ClaudeCodeVersion},
+	}
+	for _, row := range rows {
+		if row.value != "" {
+			output.WriteString(fmt.Sprintf("| %s | %s |\n", row.name, markdownCode(row.value)))
+		}
+	}
+	output.WriteString("\n")
+
+	return output.String(), nil
+}
+
+// formatMarkdownAssistant は text をそのまま、tool_use をフェンス付きブロックにする
+func formatMarkdownAssistant(data []byte, config *FilterConfig) (string, error) {
+	var msg AssistantMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	for _, content := range msg.Message.Content {
+		if !shouldDisplayContent(content, config) {
+			continue
+		}
+
+		switch content.Type {
+		case "text":
+			output.WriteString(sanitizeText(content.Text, config))
+			output.WriteString("\n\n")
+		case "tool_use":
+			output.WriteString(formatMarkdownToolUse(content, config))
+		}
+	}
+
+	return output.String(), nil
+}
+
+// formatMarkdownToolUse は tool_use をツール名とパラメータのブロックにする
+func formatMarkdownToolUse(content Content, config *FilterConfig) string {
+	var output strings.Builder
+	// ツール名も入力の一部なので、Markdown に埋め込まれた HTML として解釈されないようにする
+	output.WriteString(fmt.Sprintf("**→ %s**\n\n", html.EscapeString(sanitizeText(content.Name, config))))
+
+	// minimal モードではツール名のみ
+	if config.InfoLevel == "minimal" || len(content.Input) == 0 {
+		return output.String()
+	}
+
+	var params bytes.Buffer
+	if err := json.Indent(&params, content.Input, "", "  "); err != nil {
+		return output.String()
+	}
+	output.WriteString(fencedBlock("json", params.String()))
+	output.WriteString("\n")
+	return output.String()
+}
+
+// formatMarkdownUser は tool_result を折りたたみ可能な <details> にする
+func formatMarkdownUser(data []byte, config *FilterConfig) (string, error) {
+	var msg UserMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	for _, result := range msg.Message.Content {
+		if result.Type != "tool_result" {
+			continue
+		}
+
+		summary := "Result"
+		if config.Tracker != nil {
+			if call := config.Tracker.Call(result.ToolUseID); call != nil {
+				summary = call.Name + " result"
+			}
+		}
+		if result.IsError {
+			summary += " (error)"
+		}
+
+		// InfoLevel ごとの省略はテキスト形式と同じ
+		content := sanitizeText(result.Content, config)
+		if config.InfoLevel == "minimal" {
+			content = strings.Split(content, "\n")[0]
+		} else {
+			content = truncateLines(content, resultLimit(config, resultTool(config, result.ToolUseID)))
+		}
+
+		output.WriteString("<details>\n")
+		output.WriteString(fmt.Sprintf("<summary>← %s</summary>\n\n", html.EscapeString(sanitizeText(summary, config))))
+		output.WriteString(fencedBlock("", content))
+		output.WriteString("\n</details>\n\n")
+	}
+
+	return output.String(), nil
+}
+
+// formatMarkdownResult は最終結果とメトリクスの表を出力
+func formatMarkdownResult(data []byte, config *FilterConfig) (string, error) {
+	var msg ResultMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	output.WriteString("---\n\n## Result\n\n")
+	output.WriteString(sanitizeText(msg.Result, config))
+	output.WriteString("\n\n")
+
+	// メトリクス (standard または verbose の場合)
+	if config.InfoLevel != "minimal" {
+		output.WriteString(markdownMetricsTable(msg, config))
+	}
+
+	return output.String(), nil
+}
+
+// markdownMetricsTable はメトリクスを1行の表にする
+func markdownMetricsTable(msg ResultMessage, config *FilterConfig) string {
+	var names, separators, values []string
+	for _, field := range metricFields(msg, config) {
+		names = append(names, field.name)
+		separators = append(separators, "---")
+		values = append(values, markdownEscapeCell(field.value))
+	}
+	return "| " + strings.Join(names, " | ") + " |\n" +
+		"|" + strings.Join(separators, "|") + "|\n" +
+		"| " + strings.Join(values, " | ") + " |\n\n"
+}
+
+// fencedBlock は内容に含まれるバッククォートより長いフェンスでコードブロックを作る
+func fencedBlock(lang, content string) string {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + strings.TrimSuffix(content, "\n") + "\n" + fence + "\n"
+}
+
+// markdownCode はインラインコードとして表の中に埋め込む
+func markdownCode(s string) string {
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	return fence + markdownEscapeCell(s) + fence
+}
+
+// markdownEscapeCell は表のセルを壊す文字をエスケープ
+func markdownEscapeCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
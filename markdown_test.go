@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFormatMarkdownMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		msgType string
+		input   string
+		config  FilterConfig
+		want    string
+	}{
+		{
+			name:    "session header",
+			msgType: "system",
+			input:   `{"type":"system","subtype":"init","cwd":"/work/a|b","session_id":"s1","model":"sonnet","claude_code_version":"2.0.21"}`,
+			config:  FilterConfig{Format: "markdown"},
+			want:    "# Claude session\n\n| | |\n|---|---|\n| Session | `s1` |\n| Model | `sonnet` |\n| Working directory | `/work/a\\|b` |\n| Claude Code | `2.0.21` |\n\n",
+		},
+		{
+			name:    "non-init system message",
+			msgType: "system",
+			input:   `{"type":"system","subtype":"compact"}`,
+			config:  FilterConfig{Format: "markdown"},
+			want:    "",
+		},
+		{
+			name:    "assistant text as-is",
+			msgType: "assistant",
+			input:   `{"type":"assistant","message":{"content":[{"type":"text","text":"**bold** and ` + "`code`" + `"}]}}`,
+			config:  FilterConfig{Format: "markdown", ShowAssistant: true},
+			want:    "**bold** and `code`\n\n",
+		},
+		{
+			name:    "tool use with parameters",
+			msgType: "assistant",
+			input:   `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"ls -la"}}]}}`,
+			config:  FilterConfig{Format: "markdown", ShowTools: true, InfoLevel: "standard"},
+			want:    "**→ Bash**\n\n```json\n{\n  \"command\": \"ls -la\"\n}\n```\n\n",
+		},
+		{
+			name:    "tool use minimal",
+			msgType: "assistant",
+			input:   `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"ls -la"}}]}}`,
+			config:  FilterConfig{Format: "markdown", ShowTools: true, InfoLevel: "minimal"},
+			want:    "**→ Bash**\n\n",
+		},
+		{
+			name:    "tool result in details",
+			msgType: "user",
+			input:   `{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"denied","is_error":true}]}}`,
+			config:  FilterConfig{Format: "markdown", InfoLevel: "standard"},
+			want:    "<details>\n<summary>← Result (error)</summary>\n\n```\ndenied\n```\n\n</details>\n\n",
+		},
+		{
+			name:    "tool result truncated in standard mode",
+			msgType: "user",
+			input:   `{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"1\n2\n3\n4\n5\n6\n7"}]}}`,
+			config:  FilterConfig{Format: "markdown", InfoLevel: "standard"},
+			want:    "<details>\n<summary>← Result</summary>\n\n```\n1\n2\n3\n4\n5\n... (2 more lines)\n```\n\n</details>\n\n",
+		},
+		{
+			name:    "tool result containing a fence",
+			msgType: "user",
+			input:   `{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"` + "```go\\nx\\n```" + `"}]}}`,
+			config:  FilterConfig{Format: "markdown", InfoLevel: "verbose"},
+			want:    "<details>\n<summary>← Result</summary>\n\n````\n```go\nx\n```\n````\n\n</details>\n\n",
+		},
+		{
+			name:    "result with metrics table",
+			msgType: "result",
+			input:   `{"type":"result","subtype":"success","result":"Done","duration_ms":5000,"total_cost_usd":0.0123,"num_turns":3}`,
+			config:  FilterConfig{Format: "markdown", InfoLevel: "standard"},
+			want:    "---\n\n## Result\n\nDone\n\n| Duration | Cost | Turns |\n|---|---|---|\n| 5.0s | $0.0123 | 3 |\n\n",
+		},
+		{
+			name:    "result minimal",
+			msgType: "result",
+			input:   `{"type":"result","subtype":"success","result":"Done","duration_ms":5000,"total_cost_usd":0.0123,"num_turns":3}`,
+			config:  FilterConfig{Format: "markdown", InfoLevel: "minimal"},
+			want:    "---\n\n## Result\n\nDone\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatMessage(tt.msgType, []byte(tt.input), &tt.config)
+			if err != nil {
+				t.Fatalf("formatMessage() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("formatMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessInput_Markdown(t *testing.T) {
+	input, err := os.ReadFile("testdata/permission_denied.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := NewFilterConfig()
+	config.Format = "markdown"
+	config.UseColor = false
+
+	var output bytes.Buffer
+	if err := processInput(bytes.NewReader(input), &output, config); err != nil {
+		t.Fatalf("processInput() error = %v", err)
+	}
+
+	got := output.String()
+	for _, want := range []string{
+		"# Claude session",
+		"| Model | `claude-sonnet-4-5-20250929` |",
+		"**→ Write**",
+		"<summary>← Write result (error)</summary>",
+		"## Result",
+		"| 7.7s | $0.0180 | 4 |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("markdown output does not contain %q\nGot: %s", want, got)
+		}
+	}
+}
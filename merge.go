@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InputSource はマージ対象の入力元
+type InputSource struct {
+	Name    string    // ユーザー指定のラベル (空なら session_id から決定)
+	Path    string    // ファイルまたは FIFO のパス
+	Command string    // --run で指定されたコマンド
+	Reader  io.Reader // 標準入力などの既に開かれた入力
+}
+
+// labelColors は複数入力時にラベルへ順番に割り当てる色
+var labelColors = []string{"cyan", "green", "yellow", "blue"}
+
+// inputNamePattern は "NAME=VALUE" 形式のラベル部分
+var inputNamePattern = regexp.MustCompile(`^([A-Za-z0-9_.-]+)=(.+)$`)
+
+// parseInputSpec は "[NAME=]VALUE" 形式の指定をラベルと値に分割
+func parseInputSpec(spec string) (name, value string) {
+	if m := inputNamePattern.FindStringSubmatch(spec); m != nil {
+		return m[1], m[2]
+	}
+	return "", spec
+}
+
+// fallbackLabel は session_id が得られない場合のラベル
+func (src InputSource) fallbackLabel(index int) string {
+	if src.Path != "" {
+		return filepath.Base(src.Path)
+	}
+	return fmt.Sprintf("run%d", index+1)
+}
+
+// String はエラーメッセージ用の表示名
+func (src InputSource) String() string {
+	switch {
+	case src.Command != "":
+		return src.Command
+	case src.Reader != nil:
+		return "stdin"
+	default:
+		return src.Path
+	}
+}
+
+// sourceLine はどの入力から届いたか、いつ届いたかを伴う1行
+type sourceLine struct {
+	index int
+	text  string
+	at    time.Time
+}
+
+// processInputs は複数の入力を並行して読み、到着順にラベル付きで出力
+func processInputs(sources []InputSource, output io.Writer, config *FilterConfig) error {
+	var cast *castWriter
+	if config.CastPath != "" {
+		f, err := os.Create(config.CastPath)
+		if err != nil {
+			return fmt.Errorf("failed to create cast file: %w", err)
+		}
+		defer f.Close()
+		idle := time.Duration(config.CastIdleLimit * float64(time.Second))
+		cast = newCastWriter(f, config.CastWidth, config.CastHeight, idle)
+		output = io.MultiWriter(output, cast)
+	}
+
+	streams := make([]*stream, len(sources))
+	for i, src := range sources {
+		s := newStream(output, config)
+		if len(sources) > 1 {
+			s.prefixed = true
+			s.name = src.Name
+			s.fallback = src.fallbackLabel(i)
+			s.color = labelColors[i%len(labelColors)]
+		}
+		streams[i] = s
+	}
+	// group は入れ子にできないので、どのストリームの group が開いているかを共有する
+	if len(streams) > 0 && streams[0].github != nil {
+		group := &githubGroup{}
+		for _, s := range streams {
+			s.github.group = group
+		}
+	}
+
+	if config.RecordPath != "" {
+		if len(sources) > 1 {
+			return fmt.Errorf("--record cannot be used with multiple inputs")
+		}
+		f, err := os.Create(config.RecordPath)
+		if err != nil {
+			return fmt.Errorf("failed to create record file: %w", err)
+		}
+		defer f.Close()
+		streams[0].recorder = newRecorder(f)
+	}
+
+	lines := make(chan sourceLine)
+	errs := make(chan error, len(sources))
+
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		var p *pacer
+		if config.Command == "replay" {
+			p = newPacer(config.ReplaySpeed, time.Sleep)
+		}
+
+		wg.Add(1)
+		go func(index int, src InputSource) {
+			defer wg.Done()
+			if err := readSource(src, index, lines, p); err != nil {
+				errs <- fmt.Errorf("%s: %w", src, err)
+			}
+		}(i, src)
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+		close(errs)
+	}()
+
+	// stream の状態はこのゴルーチンからのみ更新する
+	for line := range lines {
+		if cast != nil {
+			cast.at = line.at
+		}
+		streams[line.index].processLine(line.text, line.at)
+	}
+
+	for _, s := range streams {
+		s.finish()
+	}
+
+	var all []error
+	switch {
+	case config.Format == "html":
+		if err := writeHTMLReport(output, streams); err != nil {
+			all = append(all, err)
+		}
+	case config.Format == "csv":
+		if err := writeToolCallsCSV(output, streams); err != nil {
+			all = append(all, err)
+		}
+	case config.Format == "mermaid":
+		if err := writeMermaid(output, streams); err != nil {
+			all = append(all, err)
+		}
+	case config.Format == "dot":
+		if err := writeDot(output, streams); err != nil {
+			all = append(all, err)
+		}
+	case len(sources) > 1 && config.ShowResult && isTextFormat(config.Format):
+		fmt.Fprint(output, formatCombinedSummary(streams, config))
+	}
+
+	for err := range errs {
+		all = append(all, err)
+	}
+
+	if config.StatsJSONPath != "" {
+		if err := writeStatsJSON(config.StatsJSONPath, streams); err != nil {
+			all = append(all, err)
+		}
+	}
+	if config.MetricsCSVPath != "" {
+		if err := writeMetricsCSV(config.MetricsCSVPath, streams); err != nil {
+			all = append(all, err)
+		}
+	}
+	if config.JUnitPath != "" {
+		if err := writeJUnit(config.JUnitPath, streams); err != nil {
+			all = append(all, err)
+		}
+	}
+	if config.OTLPFile != "" {
+		if err := writeOTLPFile(config.OTLPFile, streams); err != nil {
+			all = append(all, err)
+		}
+	}
+	if config.OTLPEndpoint != "" {
+		if err := sendOTLP(config.OTLPEndpoint, streams); err != nil {
+			all = append(all, err)
+		}
+	}
+	if config.TraceEventsPath != "" {
+		if err := writeTraceEvents(config.TraceEventsPath, streams); err != nil {
+			all = append(all, err)
+		}
+	}
+	if config.PromTextfile != "" {
+		if err := writePromTextfile(config.PromTextfile, streams); err != nil {
+			all = append(all, err)
+		}
+	}
+	if config.PromPushURL != "" {
+		if err := pushProm(config.PromPushURL, streams); err != nil {
+			all = append(all, err)
+		}
+	}
+	return errors.Join(all...)
+}
+
+// readSource は入力元を1行ずつ読んでチャネルへ送る
+func readSource(src InputSource, index int, lines chan<- sourceLine, p *pacer) error {
+	if src.Command != "" {
+		return readCommand(src.Command, index, lines, p)
+	}
+	if src.Reader != nil {
+		return scanLines(src.Reader, index, lines, p)
+	}
+
+	f, err := os.Open(src.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return scanLines(f, index, lines, p)
+}
+
+// readCommand はコマンドを実行し、その標準出力を読む
+func readCommand(command string, index int, lines chan<- sourceLine, p *pacer) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	scanErr := scanLines(stdout, index, lines, p)
+	// 読むのをやめるとパイプが詰まってコマンドが終わらないので、残りは読み捨てる
+	io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return err
+	}
+	return scanErr
+}
+
+// maxLineSize は1行の最大のバイト数 (大きな Read や Bash の結果も1行に収まる)
+const maxLineSize = 16 * 1024 * 1024
+
+// scanLines は r を行単位で読み、到着時刻を付けてチャネルへ送る
+// 記録済みのエンベロープは展開し、記録時の時刻を到着時刻とする
+func scanLines(r io.Reader, index int, lines chan<- sourceLine, p *pacer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		text, at := scanner.Text(), time.Now()
+		if env, ok := parseEnvelope(text); ok {
+			text, at = string(env.Line), env.Time
+			p.wait(at)
+		}
+		lines <- sourceLine{index: index, text: text, at: at}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	return nil
+}
+
+// formatCombinedSummary は全セッションの合計コストをフォーマット
+func formatCombinedSummary(streams []*stream, config *FilterConfig) string {
+	var output strings.Builder
+
+	separator := strings.Repeat("━", 40)
+	coloredSeparator := colorize(separator, roleMuted, config.colors())
+
+	output.WriteString("\n")
+	output.WriteString(coloredSeparator)
+	output.WriteString("\n")
+
+	var totalCost float64
+	completed := 0
+	for _, s := range streams {
+		output.WriteString(s.prefix())
+		result := s.config.Tracker.Result
+		if result == nil {
+			output.WriteString(colorize("(no result)", roleMuted, config.colors()))
+			output.WriteString("\n")
+			continue
+		}
+		completed++
+		totalCost += result.TotalCostUsd
+		output.WriteString(colorize(formatMetrics(*result, config), roleMetrics, config.colors()))
+		output.WriteString("\n")
+	}
+
+	total := fmt.Sprintf("Total Cost: $%.4f | Sessions: %d/%d completed", totalCost, completed, len(streams))
+	output.WriteString(colorize(total, roleMetrics, config.colors()))
+	output.WriteString("\n")
+	output.WriteString(coloredSeparator)
+	output.WriteString("\n")
+
+	return output.String()
+}
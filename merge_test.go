@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseInputSpec(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		wantName  string
+		wantValue string
+	}{
+		{
+			name:      "path only",
+			spec:      "logs/agent.jsonl",
+			wantName:  "",
+			wantValue: "logs/agent.jsonl",
+		},
+		{
+			name:      "named path",
+			spec:      "api=logs/agent.jsonl",
+			wantName:  "api",
+			wantValue: "logs/agent.jsonl",
+		},
+		{
+			name:      "named command",
+			spec:      `web=claude -p "hello world"`,
+			wantName:  "web",
+			wantValue: `claude -p "hello world"`,
+		},
+		{
+			name:      "value containing spaces before equals is not a name",
+			spec:      "echo a=b",
+			wantName:  "",
+			wantValue: "echo a=b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotName, gotValue := parseInputSpec(tt.spec)
+			if gotName != tt.wantName || gotValue != tt.wantValue {
+				t.Errorf("parseInputSpec() = (%q, %q), want (%q, %q)", gotName, gotValue, tt.wantName, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestProcessInputs_Merge(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.jsonl")
+	second := filepath.Join(dir, "second.jsonl")
+
+	writeFile(t, first, `{"type":"system","subtype":"init","session_id":"aaaaaaaa-1111"}
+{"type":"assistant","message":{"content":[{"type":"text","text":"from first"}]},"session_id":"aaaaaaaa-1111"}
+{"type":"result","subtype":"success","result":"first done","duration_ms":1000,"total_cost_usd":0.01,"num_turns":1,"session_id":"aaaaaaaa-1111"}
+`)
+	writeFile(t, second, `{"type":"assistant","message":{"content":[{"type":"text","text":"from second"}]},"session_id":"bbbbbbbb-2222"}
+{"type":"result","subtype":"success","result":"second done","duration_ms":2000,"total_cost_usd":0.02,"num_turns":2,"session_id":"bbbbbbbb-2222"}
+`)
+
+	config := NewFilterConfig()
+	config.UseColor = false
+	sources := []InputSource{
+		{Path: first},
+		{Name: "web", Path: second},
+	}
+
+	var output bytes.Buffer
+	if err := processInputs(sources, &output, config); err != nil {
+		t.Fatalf("processInputs() error = %v", err)
+	}
+
+	got := output.String()
+	for _, want := range []string{
+		"[aaaaaaaa] from first",
+		"[web] from second",
+		"[aaaaaaaa] first done",
+		"[web] second done",
+		"Total Cost: $0.0300 | Sessions: 2/2 completed",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("processInputs() output does not contain %q\nGot: %s", want, got)
+		}
+	}
+}
+
+func TestProcessInputs_SingleInputHasNoPrefix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "only.jsonl")
+	writeFile(t, path, `{"type":"assistant","message":{"content":[{"type":"text","text":"Hello"}]},"session_id":"aaaaaaaa-1111"}
+`)
+
+	config := NewFilterConfig()
+	config.UseColor = false
+
+	var output bytes.Buffer
+	if err := processInputs([]InputSource{{Path: path}}, &output, config); err != nil {
+		t.Fatalf("processInputs() error = %v", err)
+	}
+	if got := output.String(); got != "Hello\n" {
+		t.Errorf("processInputs() = %q, want %q", got, "Hello\n")
+	}
+}
+
+func TestProcessInputs_Command(t *testing.T) {
+	config := NewFilterConfig()
+	config.UseColor = false
+	sources := []InputSource{
+		{Name: "a", Command: `printf '%s\n' '{"type":"assistant","message":{"content":[{"type":"text","text":"one"}]}}'`},
+		{Name: "b", Command: `printf '%s\n' '{"type":"assistant","message":{"content":[{"type":"text","text":"two"}]}}'`},
+	}
+
+	var output bytes.Buffer
+	if err := processInputs(sources, &output, config); err != nil {
+		t.Fatalf("processInputs() error = %v", err)
+	}
+
+	got := output.String()
+	for _, want := range []string{"[a] one", "[b] two", "[a] (no result)", "Sessions: 0/2 completed"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("processInputs() output does not contain %q\nGot: %s", want, got)
+		}
+	}
+}
+
+func TestProcessInputs_LongLine(t *testing.T) {
+	config := NewFilterConfig()
+	config.UseColor = false
+	config.InfoLevel = "verbose"
+
+	long := strings.Repeat("x", 1024*1024)
+	input := `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"a.txt"}}]}}
+{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"` + long + `"}]}}
+{"type":"assistant","message":{"content":[{"type":"text","text":"after"}]}}
+`
+
+	var output bytes.Buffer
+	if err := processInputs([]InputSource{{Reader: strings.NewReader(input)}}, &output, config); err != nil {
+		t.Fatalf("processInputs() error = %v", err)
+	}
+	if !strings.Contains(output.String(), "after") {
+		t.Errorf("lines after a long line are missing:\n%.200s", output.String())
+	}
+}
+
+func TestProcessInputs_CommandTooLongLine(t *testing.T) {
+	config := NewFilterConfig()
+	config.UseColor = false
+	// 1行の上限を超える行の後も出力を続けるコマンド
+	command := fmt.Sprintf(`head -c %d /dev/zero | tr '\0' x; echo; seq 3000`, maxLineSize+1024*1024)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- processInputs([]InputSource{{Command: command}}, io.Discard, config)
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "token too long") {
+			t.Errorf("processInputs() error = %v, want token too long", err)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("processInputs() did not return after a read error")
+	}
+}
+
+func TestProcessInputs_MissingFile(t *testing.T) {
+	config := NewFilterConfig()
+	config.UseColor = false
+
+	var output bytes.Buffer
+	err := processInputs([]InputSource{{Path: filepath.Join(t.TempDir(), "missing.jsonl")}}, &output, config)
+	if err == nil {
+		t.Error("processInputs() should return error for missing file")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// OTLP/JSON のスパン種別とステータス
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusOK         = 1
+	otlpStatusError      = 2
+)
+
+// otlpTraces は OTLP/JSON の ExportTraceServiceRequest
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+// otlpSpan は1つのスパン (時刻は OTLP/JSON の規約に従い文字列のナノ秒)
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+// otlpValue は AnyValue (int64 は文字列で表す)
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func stringAttr(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func boolAttr(key string, value bool) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{BoolValue: &value}}
+}
+
+func intAttr(key string, value int) otlpAttribute {
+	s := strconv.Itoa(value)
+	return otlpAttribute{Key: key, Value: otlpValue{IntValue: &s}}
+}
+
+func doubleAttr(key string, value float64) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{DoubleValue: &value}}
+}
+
+// newOTLPTraces は全セッションを OTLP のトレースに変換 (1セッション1トレース)
+func newOTLPTraces(streams []*stream) otlpTraces {
+	var spans []otlpSpan
+	for _, s := range streams {
+		spans = append(spans, sessionSpans(s.config.Tracker)...)
+	}
+	return otlpTraces{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			stringAttr("service.name", "claude-code"),
+		}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "ccfilter"},
+			Spans: spans,
+		}},
+	}}}
+}
+
+// sessionSpans はセッションをルートスパン、ターンとツール呼び出しを子孫スパンに変換
+// サブエージェントのターンは呼び出し元の Task のスパンの子になる
+func sessionSpans(t *Tracker) []otlpSpan {
+	seed := t.SessionID()
+	if seed == "" {
+		seed = randomHex(16)
+	}
+	traceID := hashID(seed, 16)
+
+	root := otlpSpan{
+		TraceID:           traceID,
+		SpanID:            hashID(seed+"/session", 8),
+		Name:              "claude session",
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: unixNano(t.Start),
+		EndTimeUnixNano:   unixNano(t.End),
+		Attributes:        sessionAttributes(t),
+		Status:            otlpStatus{Code: otlpStatusOK},
+	}
+	if t.Result == nil {
+		root.Status = otlpStatus{Code: otlpStatusError, Message: "no result message"}
+	} else if t.Result.IsError {
+		root.Status = otlpStatus{Code: otlpStatusError, Message: t.Result.Subtype}
+	}
+	spans := []otlpSpan{root}
+
+	// スパン ID は出現順の番号から作る (message id を持たないターンもあるため)
+	var walk func(step *Step, parentSpanID string)
+	walk = func(step *Step, parentSpanID string) {
+		spanID := hashID(seed+"/"+strconv.Itoa(len(spans)), 8)
+		span := stepSpan(step, traceID, spanID, parentSpanID)
+		spans = append(spans, span)
+		for _, child := range t.children(step) {
+			walk(child, span.SpanID)
+		}
+	}
+	for _, step := range t.Steps {
+		if step.Kind == "turn" && step.ParentID == "" {
+			walk(step, root.SpanID)
+		}
+	}
+	return spans
+}
+
+// sessionAttributes はセッションのスパンの属性 (モデルごとの使用量を含む)
+func sessionAttributes(t *Tracker) []otlpAttribute {
+	stats := computeStats(t)
+	attrs := []otlpAttribute{
+		stringAttr("gen_ai.system", "anthropic"),
+		stringAttr("session.id", stats.SessionID),
+		intAttr("gen_ai.usage.input_tokens", stats.Tokens.InputTokens),
+		intAttr("gen_ai.usage.output_tokens", stats.Tokens.OutputTokens),
+		intAttr("claude.usage.cache_read_input_tokens", stats.Tokens.CacheReadInputTokens),
+		intAttr("claude.usage.cache_creation_input_tokens", stats.Tokens.CacheCreationInputTokens),
+		doubleAttr("claude.cost_usd", stats.CostUsd),
+		intAttr("claude.tool_errors", stats.Errors),
+		intAttr("claude.permission_denials", stats.PermissionDenials),
+	}
+	if t.Init != nil {
+		attrs = append(attrs,
+			stringAttr("gen_ai.request.model", t.Init.Model),
+			stringAttr("claude.cwd", t.Init.Cwd),
+			stringAttr("claude.version", t.Init.ClaudeCodeVersion),
+		)
+	}
+	if t.Result != nil {
+		attrs = append(attrs,
+			boolAttr("claude.is_error", t.Result.IsError),
+			intAttr("claude.num_turns", t.Result.NumTurns),
+			intAttr("claude.duration_ms", t.Result.DurationMs),
+			intAttr("claude.duration_api_ms", t.Result.DurationAPIMs),
+		)
+	}
+	for _, name := range sortedKeys(stats.Models) {
+		model := stats.Models[name]
+		prefix := "claude.model." + name + "."
+		attrs = append(attrs,
+			intAttr(prefix+"input_tokens", model.InputTokens),
+			intAttr(prefix+"output_tokens", model.OutputTokens),
+			intAttr(prefix+"cache_read_input_tokens", model.CacheReadInputTokens),
+			intAttr(prefix+"cache_creation_input_tokens", model.CacheCreationInputTokens),
+			doubleAttr(prefix+"cost_usd", model.CostUSD),
+		)
+	}
+	return attrs
+}
+
+// stepSpan はターンまたはツール呼び出しのスパンを作成
+func stepSpan(step *Step, traceID, spanID, parentSpanID string) otlpSpan {
+	span := otlpSpan{
+		TraceID:           traceID,
+		SpanID:            spanID,
+		ParentSpanID:      parentSpanID,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: unixNano(step.Start),
+		EndTimeUnixNano:   unixNano(step.End),
+	}
+
+	switch step.Kind {
+	case "turn":
+		span.Name = "turn"
+		if step.Name != "" {
+			span.Name += " " + step.Name
+		}
+		span.Attributes = []otlpAttribute{
+			stringAttr("gen_ai.response.model", step.Name),
+			stringAttr("gen_ai.response.id", step.ID),
+			intAttr("gen_ai.usage.input_tokens", step.Usage.InputTokens),
+			intAttr("gen_ai.usage.output_tokens", step.Usage.OutputTokens),
+			intAttr("claude.usage.cache_read_input_tokens", step.Usage.CacheReadInputTokens),
+			intAttr("claude.usage.cache_creation_input_tokens", step.Usage.CacheCreationInputTokens),
+		}
+		if step.ParentID != "" {
+			span.Attributes = append(span.Attributes, boolAttr("claude.subagent", true))
+		}
+	case "tool":
+		span.Name = "tool " + step.Name
+		span.Attributes = []otlpAttribute{
+			stringAttr("gen_ai.tool.name", step.Name),
+			stringAttr("gen_ai.tool.call.id", step.ID),
+			boolAttr("claude.tool.is_error", step.IsError),
+		}
+		if _, param, ok := mainParam(step.Name, step.Input); ok {
+			span.Attributes = append(span.Attributes, stringAttr("claude.tool.parameter", param))
+		}
+		switch {
+		case step.IsError:
+			span.Status = otlpStatus{Code: otlpStatusError, Message: firstLine(step.Result)}
+		case !step.Done:
+			span.Status = otlpStatus{Code: otlpStatusError, Message: "no tool result"}
+		}
+	}
+	return span
+}
+
+// writeOTLPFile は全セッションを OTLP/JSON のトレースファイルに書き出す
+func writeOTLPFile(path string, streams []*stream) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create trace file: %w", err)
+	}
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(newOTLPTraces(streams))
+}
+
+// sendOTLP は全セッションのトレースを OTLP/HTTP (JSON) のエンドポイントへ送信
+// パスを省略した場合は標準の /v1/traces に送る
+func sendOTLP(endpoint string, streams []*stream) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+
+	body, err := json.Marshal(newOTLPTraces(streams))
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(u.String(), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send traces: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to send traces: %s", resp.Status)
+	}
+	return nil
+}
+
+// hashID は seed から決まった長さ (バイト数) の ID を16進数で作る
+// 同じセッションを再送しても同じトレースになるようにする
+func hashID(seed string, size int) string {
+	sum := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(sum[:size])
+}
+
+// randomHex は n バイトのランダムな16進数文字列を返す
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// unixNano は時刻を OTLP/JSON のナノ秒の文字列にする
+func unixNano(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// subagentSessionLines はサブエージェントを含むセッション
+var subagentSessionLines = []string{
+	`{"type":"system","subtype":"init","session_id":"s1","model":"sonnet"}`,
+	`{"type":"assistant","message":{"id":"m1","model":"sonnet","usage":{"input_tokens":10,"output_tokens":5},"content":[{"type":"tool_use","id":"task1","name":"Task","input":{"description":"explore"}}]}}`,
+	`{"type":"assistant","message":{"id":"s1m1","model":"haiku","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"a.go"}}]},"parent_tool_use_id":"task1"}`,
+	`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"no such file","is_error":true}]},"parent_tool_use_id":"task1"}`,
+	`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"task1","content":"explored"}]}}`,
+	`{"type":"result","subtype":"success","result":"done","session_id":"s1","total_cost_usd":0.02,"modelUsage":{"haiku":{"inputTokens":3,"outputTokens":4,"costUSD":0.005}}}`,
+}
+
+func TestSessionSpans(t *testing.T) {
+	tracker := trackLines(t, []int{0, 1, 2, 4, 6, 7}, subagentSessionLines)
+
+	spans := sessionSpans(tracker)
+
+	type want struct {
+		name   string
+		parent int // spans 内の親の位置 (-1 はルート)
+		status int
+	}
+	wants := []want{
+		{"claude session", -1, otlpStatusOK},
+		{"turn sonnet", 0, 0},
+		{"tool Task", 1, 0},
+		{"turn haiku", 2, 0},
+		{"tool Read", 3, otlpStatusError},
+	}
+	if len(spans) != len(wants) {
+		t.Fatalf("got %d spans, want %d", len(spans), len(wants))
+	}
+	for i, w := range wants {
+		span := spans[i]
+		if span.Name != w.name {
+			t.Errorf("span[%d].Name = %q, want %q", i, span.Name, w.name)
+		}
+		if span.TraceID != spans[0].TraceID || len(span.TraceID) != 32 || len(span.SpanID) != 16 {
+			t.Errorf("span[%d] ids = %q/%q", i, span.TraceID, span.SpanID)
+		}
+		wantParent := ""
+		if w.parent >= 0 {
+			wantParent = spans[w.parent].SpanID
+		}
+		if span.ParentSpanID != wantParent {
+			t.Errorf("span[%d].ParentSpanID = %q, want %q", i, span.ParentSpanID, wantParent)
+		}
+		if span.Status.Code != w.status {
+			t.Errorf("span[%d].Status = %+v, want code %d", i, span.Status, w.status)
+		}
+	}
+
+	// Read は 2s 目に呼ばれ 4s 目に結果が届いた
+	read := spans[4]
+	if got := attr(read, "gen_ai.tool.name"); got != "Read" {
+		t.Errorf("gen_ai.tool.name = %v", got)
+	}
+	if got := attr(read, "claude.tool.is_error"); got != true {
+		t.Errorf("claude.tool.is_error = %v", got)
+	}
+	start, end := unixNano(tracker.Start.Add(2*time.Second)), unixNano(tracker.Start.Add(4*time.Second))
+	if read.StartTimeUnixNano != start || read.EndTimeUnixNano != end {
+		t.Errorf("Read span = %s..%s, want %s..%s", read.StartTimeUnixNano, read.EndTimeUnixNano, start, end)
+	}
+
+	if got := attr(spans[1], "gen_ai.usage.input_tokens"); got != "10" {
+		t.Errorf("turn input tokens = %v", got)
+	}
+	if got := attr(spans[0], "claude.cost_usd"); got != 0.02 {
+		t.Errorf("claude.cost_usd = %v", got)
+	}
+	if got := attr(spans[0], "claude.model.haiku.cost_usd"); got != 0.005 {
+		t.Errorf("claude.model.haiku.cost_usd = %v", got)
+	}
+
+	// 同じセッションからは同じ ID が作られる
+	if again := sessionSpans(tracker); again[4].SpanID != read.SpanID {
+		t.Error("span ids should be stable across exports")
+	}
+}
+
+// attr はスパンの属性の値を返す
+func attr(span otlpSpan, key string) interface{} {
+	for _, a := range span.Attributes {
+		if a.Key != key {
+			continue
+		}
+		switch {
+		case a.Value.StringValue != nil:
+			return *a.Value.StringValue
+		case a.Value.IntValue != nil:
+			return *a.Value.IntValue
+		case a.Value.BoolValue != nil:
+			return *a.Value.BoolValue
+		case a.Value.DoubleValue != nil:
+			return *a.Value.DoubleValue
+		}
+	}
+	return nil
+}
+
+func TestSendOTLP(t *testing.T) {
+	var received otlpTraces
+	var path, contentType string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("collector received invalid JSON: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	tracker := trackLines(t, []int{0, 1, 2, 4, 6, 7}, subagentSessionLines)
+	streams := []*stream{{config: &FilterConfig{Tracker: tracker}}}
+
+	if err := sendOTLP(collector.URL, streams); err != nil {
+		t.Fatalf("sendOTLP() error = %v", err)
+	}
+	if path != "/v1/traces" || contentType != "application/json" {
+		t.Errorf("request = %s (%s), want /v1/traces (application/json)", path, contentType)
+	}
+	if n := len(received.ResourceSpans[0].ScopeSpans[0].Spans); n != 5 {
+		t.Errorf("collector received %d spans, want 5", n)
+	}
+}
+
+func TestSendOTLP_ErrorStatus(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+
+	streams := []*stream{{config: &FilterConfig{Tracker: newTracker()}}}
+	if err := sendOTLP(collector.URL+"/custom/traces", streams); err == nil {
+		t.Error("sendOTLP() should fail when the collector rejects the request")
+	}
+}
+
+func TestWriteOTLPFile(t *testing.T) {
+	input, err := os.Open("testdata/permission_denied.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer input.Close()
+
+	path := filepath.Join(t.TempDir(), "trace.json")
+	config := NewFilterConfig()
+	config.OTLPFile = path
+	if err := processInput(input, io.Discard, config); err != nil {
+		t.Fatalf("processInput() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("trace file was not written: %v", err)
+	}
+	var traces otlpTraces
+	if err := json.Unmarshal(data, &traces); err != nil {
+		t.Fatalf("trace file is not valid OTLP/JSON: %v", err)
+	}
+	spans := traces.ResourceSpans[0].ScopeSpans[0].Spans
+	// session, 2 turns, Write
+	if len(spans) != 4 {
+		t.Errorf("got %d spans, want 4", len(spans))
+	}
+}
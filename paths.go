@@ -0,0 +1,64 @@
+package main
+
+import "strings"
+
+// relativizePaths は文字列中のパスを短く表示する
+// セッションの作業ディレクトリ (init メッセージの cwd) 以下は相対パスに、ホームディレクトリ以下は ~ からのパスにする
+// config.AbsolutePaths の場合はそのまま返す
+func relativizePaths(s string, config *FilterConfig) string {
+	if config.AbsolutePaths {
+		return s
+	}
+	if config.Tracker != nil && config.Tracker.Init != nil {
+		s = replacePathRoot(s, config.Tracker.Init.Cwd, "")
+	}
+	return replacePathRoot(s, config.HomeDir, "~")
+}
+
+// replacePathRoot は s の中でディレクトリ root から始まるパスの root の部分を置き換える
+// replacement が空の場合は root 以下を相対パスにする (root 自体は ".")
+// /home/u/project のように root の名前の途中で終わるパスや、別のパスの途中に現れる root は置き換えない
+func replacePathRoot(s, root, replacement string) string {
+	root = strings.TrimRight(root, "/")
+	if root == "" || !strings.Contains(s, root) {
+		return s
+	}
+
+	var output strings.Builder
+	for {
+		i := strings.Index(s, root)
+		if i < 0 {
+			break
+		}
+		end := i + len(root)
+		if (i > 0 && isPathByte(s[i-1])) || (end < len(s) && s[end] != '/' && isPathByte(s[end])) {
+			output.WriteString(s[:end])
+			s = s[end:]
+			continue
+		}
+
+		output.WriteString(s[:i])
+		switch {
+		case replacement != "":
+			output.WriteString(replacement)
+		case end+1 < len(s) && s[end] == '/' && isPathByte(s[end+1]) && s[end+1] != '.':
+			end++ // "root/" を取り除いて相対パスにする (. で始まる場合は ./... のように ./ を残す)
+		default:
+			output.WriteString(".")
+		}
+		s = s[end:]
+	}
+	output.WriteString(s)
+	return output.String()
+}
+
+// isPathByte はパスの一部として続く文字かどうかを判定 (UTF-8 の多バイト文字も含む)
+func isPathByte(b byte) bool {
+	switch {
+	case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		return true
+	case b == '.' || b == '_' || b == '-' || b == '~' || b == '/':
+		return true
+	}
+	return b >= 0x80
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRelativizePaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		cwd      string
+		home     string
+		absolute bool
+		input    string
+		want     string
+	}{
+		{name: "file inside cwd", cwd: "/home/pankona/src/ccfilter", input: "/home/pankona/src/ccfilter/hello.go", want: "hello.go"},
+		{name: "nested file", cwd: "/home/pankona/src/ccfilter", input: "/home/pankona/src/ccfilter/cmd/main.go", want: "cmd/main.go"},
+		{name: "cwd itself", cwd: "/home/pankona/src/ccfilter", input: "cd /home/pankona/src/ccfilter && go test", want: "cd . && go test"},
+		{name: "cwd with trailing slash", cwd: "/home/pankona/src/ccfilter/", input: "ls /home/pankona/src/ccfilter/", want: "ls ./"},
+		{name: "several paths in a command", cwd: "/work", input: "diff /work/a.txt /work/b.txt", want: "diff a.txt b.txt"},
+		{name: "paths in results", cwd: "/work", input: "/work/a.go:12: undefined: x\n/work/b.go:3: ok", want: "a.go:12: undefined: x\nb.go:3: ok"},
+		{name: "dot files keep ./", cwd: "/work", input: "cat /work/.env && go test /work/...", want: "cat ./.env && go test ./..."},
+		{name: "quoted path", cwd: "/work", input: `cat "/work/my file.txt"`, want: `cat "my file.txt"`},
+		{name: "sibling with the same prefix", cwd: "/home/pankona/src/ccfilter", input: "/home/pankona/src/ccfilter2/main.go", want: "/home/pankona/src/ccfilter2/main.go"},
+		{name: "cwd inside another path", cwd: "/work", input: "/mnt/work/a.go", want: "/mnt/work/a.go"},
+		{name: "home directory", cwd: "/home/pankona/src/ccfilter", home: "/home/pankona", input: "/home/pankona/.config/ccfilter/config.json", want: "~/.config/ccfilter/config.json"},
+		{name: "cwd before home", cwd: "/home/pankona/src/ccfilter", home: "/home/pankona", input: "cp /home/pankona/notes.md /home/pankona/src/ccfilter/docs/", want: "cp ~/notes.md docs/"},
+		{name: "home itself", home: "/home/pankona", input: "cd /home/pankona", want: "cd ~"},
+		{name: "no cwd", input: "/home/pankona/src/ccfilter/hello.go", want: "/home/pankona/src/ccfilter/hello.go"},
+		{name: "root cwd", cwd: "/", input: "/etc/hosts", want: "/etc/hosts"},
+		{name: "absolute paths", cwd: "/work", home: "/home/pankona", absolute: true, input: "/work/a.go /home/pankona/b.go", want: "/work/a.go /home/pankona/b.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewFilterConfig()
+			config.Tracker = newTracker()
+			if tt.cwd != "" {
+				config.Tracker.Init = &SystemMessage{Cwd: tt.cwd}
+			}
+			config.HomeDir = tt.home
+			config.AbsolutePaths = tt.absolute
+
+			if got := relativizePaths(tt.input, config); got != tt.want {
+				t.Errorf("relativizePaths(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessInput_RelativePaths(t *testing.T) {
+	input := strings.Join([]string{
+		`{"type":"system","subtype":"init","cwd":"/home/pankona/go/src/github.com/pankona/ccfilter","session_id":"s1","tools":[],"model":"claude"}`,
+		`{"type":"assistant","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/home/pankona/go/src/github.com/pankona/ccfilter/hello.go"}}]},"session_id":"s1"}`,
+		`{"type":"assistant","message":{"id":"m2","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"go vet /home/pankona/go/src/github.com/pankona/ccfilter/..."}}]},"session_id":"s1"}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t2","content":"/home/pankona/go/src/github.com/pankona/ccfilter/hello.go:3:1: missing return"}]},"session_id":"s1"}`,
+	}, "\n") + "\n"
+
+	tests := []struct {
+		name     string
+		absolute bool
+		want     []string
+	}{
+		{
+			name: "relative",
+			want: []string{
+				`→ Read: file_path="hello.go"`,
+				`→ Bash: command="go vet ./..."`,
+				`← hello.go:3:1: missing return`,
+			},
+		},
+		{
+			name:     "absolute",
+			absolute: true,
+			want: []string{
+				`→ Read: file_path="/home/pankona/go/src/github.com/pankona/ccfilter/hello.go"`,
+				`← /home/pankona/go/src/github.com/pankona/ccfilter/hello.go:3:1: missing return`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewFilterConfig()
+			config.UseColor = false
+			config.Width = 0
+			config.AbsolutePaths = tt.absolute
+
+			var output bytes.Buffer
+			if err := processInput(strings.NewReader(input), &output, config); err != nil {
+				t.Fatalf("processInput() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(output.String(), want) {
+					t.Errorf("output does not contain %q:\n%s", want, output.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// promDurationBuckets はセッション所要時間のヒストグラムの上限 (秒)
+var promDurationBuckets = []float64{10, 30, 60, 120, 300, 600, 1800, 3600}
+
+// promMetric は Prometheus のテキスト形式で出力する1メトリクス
+// textfile も Pushgateway (PUT) も実行ごとに値を置き換えるので、件数や合計は直近の実行の値を表す gauge にする
+// (実行ごとに 0 から数え直した値を counter にすると rate() や increase() が正しく計算できない)
+type promMetric struct {
+	name    string
+	help    string
+	kind    string // "gauge" or "histogram"
+	samples []promSample
+}
+
+// promSample はラベル付きの1サンプル
+type promSample struct {
+	suffix string   // "_bucket" など
+	labels []string // name, value の組
+	value  float64
+}
+
+// writePromTextfile は node_exporter の textfile collector 用のメトリクスを書き出す
+// collector が書き込み途中のファイルを読まないよう、一時ファイルに書いてから置き換える
+func writePromTextfile(path string, streams []*stream) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics textfile: %w", err)
+	}
+	if err := encodeProm(f, streams); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// pushProm は Pushgateway 互換のエンドポイントへメトリクスを送信
+// パスを省略した場合は /metrics/job/ccfilter に送る
+func pushProm(endpoint string, streams []*stream) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid pushgateway URL: %w", err)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/metrics/job/ccfilter"
+	}
+
+	var body bytes.Buffer
+	if err := encodeProm(&body, streams); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, u.String(), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to push metrics: %s", resp.Status)
+	}
+	return nil
+}
+
+// encodeProm は全セッションを集計して Prometheus のテキスト形式で書き出す
+func encodeProm(w io.Writer, streams []*stream) error {
+	var output strings.Builder
+	for _, m := range promMetrics(streams) {
+		output.WriteString(fmt.Sprintf("# HELP %s %s\n", m.name, m.help))
+		output.WriteString(fmt.Sprintf("# TYPE %s %s\n", m.name, m.kind))
+		for _, s := range m.samples {
+			output.WriteString(m.name + s.suffix + promLabels(s.labels) + " " + promValue(s.value) + "\n")
+		}
+	}
+	_, err := io.WriteString(w, output.String())
+	return err
+}
+
+// promMetrics は全セッションのメトリクスを集計
+func promMetrics(streams []*stream) []promMetric {
+	toolCalls := make(map[[2]string]int) // tool, error
+	tokens := make(map[[2]string]int)    // model, kind
+	cost := make(map[string]float64)     // model
+	sessions := make(map[string]int)     // status
+	var durations []float64
+
+	for _, s := range streams {
+		t := s.config.Tracker
+		stats := computeStats(t)
+
+		for _, step := range t.Steps {
+			if step.Kind == "tool" {
+				toolCalls[[2]string{step.Name, strconv.FormatBool(step.IsError)}]++
+			}
+		}
+
+		var modelCost float64
+		for name, model := range stats.Models {
+			tokens[[2]string{name, "input"}] += model.InputTokens
+			tokens[[2]string{name, "output"}] += model.OutputTokens
+			tokens[[2]string{name, "cache_read"}] += model.CacheReadInputTokens
+			tokens[[2]string{name, "cache_creation"}] += model.CacheCreationInputTokens
+			if model.CostUSD > 0 {
+				cost[name] += model.CostUSD
+				modelCost += model.CostUSD
+			}
+		}
+		// modelUsage がない場合は合計コストを init のモデルに計上する
+		if modelCost == 0 && stats.CostUsd > 0 {
+			model := "unknown"
+			if t.Init != nil && t.Init.Model != "" {
+				model = t.Init.Model
+			}
+			cost[model] += stats.CostUsd
+		}
+
+		switch {
+		case t.Result == nil:
+			sessions["incomplete"]++
+		case t.Result.IsError:
+			sessions["error"]++
+			durations = append(durations, float64(t.Result.DurationMs)/1000)
+		default:
+			sessions["success"]++
+			durations = append(durations, float64(t.Result.DurationMs)/1000)
+		}
+	}
+
+	metrics := []promMetric{
+		{name: "ccfilter_sessions", help: "Claude sessions in the last run by final status.", kind: "gauge"},
+		{name: "ccfilter_tool_calls", help: "Tool calls in the last run by tool and error status.", kind: "gauge"},
+		{name: "ccfilter_tokens", help: "Tokens used in the last run by model and kind.", kind: "gauge"},
+		{name: "ccfilter_cost_usd", help: "Cost in USD of the last run by model.", kind: "gauge"},
+		{name: "ccfilter_session_duration_seconds", help: "Duration of the completed sessions in the last run.", kind: "histogram"},
+	}
+
+	for _, status := range sortedKeys(sessions) {
+		metrics[0].samples = append(metrics[0].samples, promSample{labels: []string{"status", status}, value: float64(sessions[status])})
+	}
+	for _, key := range sortedPairs(toolCalls) {
+		metrics[1].samples = append(metrics[1].samples, promSample{labels: []string{"tool", key[0], "error", key[1]}, value: float64(toolCalls[key])})
+	}
+	for _, key := range sortedPairs(tokens) {
+		metrics[2].samples = append(metrics[2].samples, promSample{labels: []string{"model", key[0], "kind", key[1]}, value: float64(tokens[key])})
+	}
+	for _, model := range sortedKeys(cost) {
+		metrics[3].samples = append(metrics[3].samples, promSample{labels: []string{"model", model}, value: cost[model]})
+	}
+
+	var sum float64
+	for _, d := range durations {
+		sum += d
+	}
+	for _, le := range promDurationBuckets {
+		count := 0
+		for _, d := range durations {
+			if d <= le {
+				count++
+			}
+		}
+		metrics[4].samples = append(metrics[4].samples, promSample{suffix: "_bucket", labels: []string{"le", promValue(le)}, value: float64(count)})
+	}
+	metrics[4].samples = append(metrics[4].samples,
+		promSample{suffix: "_bucket", labels: []string{"le", "+Inf"}, value: float64(len(durations))},
+		promSample{suffix: "_sum", value: sum},
+		promSample{suffix: "_count", value: float64(len(durations))},
+	)
+
+	return metrics
+}
+
+// sortedPairs は2つ組のキーをソートして返す
+func sortedPairs(m map[[2]string]int) [][2]string {
+	keys := make([][2]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
+
+// promLabels はラベルを {name="value",...} 形式にする
+func promLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var parts []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, labels[i], promEscape(labels[i+1])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// promEscape はラベルの値をエスケープ
+func promEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+// promValue はサンプルの値をフォーマット
+func promValue(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncodeProm(t *testing.T) {
+	a := trackLines(t, []int{0, 1, 2, 4, 6, 7}, subagentSessionLines)
+	b := trackLines(t, []int{0, 1, 2}, []string{
+		`{"type":"system","subtype":"init","session_id":"s2","model":"opus"}`,
+		`{"type":"assistant","message":{"id":"m1","model":"opus","usage":{"input_tokens":7,"output_tokens":2},"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"ls"}}]}}`,
+		`{"type":"result","subtype":"error_during_execution","is_error":true,"duration_ms":45000,"total_cost_usd":0.5,"session_id":"s2"}`,
+	})
+	c := trackLines(t, []int{0}, []string{
+		`{"type":"assistant","message":{"id":"m1","model":"opus","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"ls"}}]}}`,
+	})
+
+	var output bytes.Buffer
+	streams := []*stream{
+		{config: &FilterConfig{Tracker: a}},
+		{config: &FilterConfig{Tracker: b}},
+		{config: &FilterConfig{Tracker: c}},
+	}
+	if err := encodeProm(&output, streams); err != nil {
+		t.Fatalf("encodeProm() error = %v", err)
+	}
+	got := output.String()
+
+	for _, want := range []string{
+		"# TYPE ccfilter_tool_calls gauge\n",
+		`ccfilter_sessions{status="error"} 1`,
+		`ccfilter_sessions{status="incomplete"} 1`,
+		`ccfilter_sessions{status="success"} 1`,
+		`ccfilter_tool_calls{tool="Bash",error="false"} 2`,
+		`ccfilter_tool_calls{tool="Read",error="true"} 1`,
+		`ccfilter_tool_calls{tool="Task",error="false"} 1`,
+		`ccfilter_tokens{model="haiku",kind="output"} 4`,
+		`ccfilter_tokens{model="opus",kind="input"} 7`,
+		`ccfilter_cost_usd{model="haiku"} 0.005`,
+		`ccfilter_cost_usd{model="opus"} 0.5`,
+		"# TYPE ccfilter_session_duration_seconds histogram\n",
+		`ccfilter_session_duration_seconds_bucket{le="30"} 1`,
+		`ccfilter_session_duration_seconds_bucket{le="60"} 2`,
+		`ccfilter_session_duration_seconds_bucket{le="+Inf"} 2`,
+		"ccfilter_session_duration_seconds_sum 45\n",
+		"ccfilter_session_duration_seconds_count 2\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, got)
+		}
+	}
+	// 実行ごとに置き換える値なので counter や _total は使わない
+	for _, unwanted := range []string{"_total", " counter\n"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("metrics contain %q:\n%s", unwanted, got)
+		}
+	}
+}
+
+func TestPromLabels(t *testing.T) {
+	got := promLabels([]string{"tool", `mcp__x"y\z`, "error", "a\nb"})
+	want := `{tool="mcp__x\"y\\z",error="a\nb"}`
+	if got != want {
+		t.Errorf("promLabels() = %s, want %s", got, want)
+	}
+}
+
+func TestWritePromTextfile(t *testing.T) {
+	input, err := os.Open("testdata/permission_denied.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer input.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ccfilter.prom")
+	config := NewFilterConfig()
+	config.PromTextfile = path
+	if err := processInput(input, io.Discard, config); err != nil {
+		t.Fatalf("processInput() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("textfile was not written: %v", err)
+	}
+	if !strings.Contains(string(data), `ccfilter_tool_calls{tool="Write",error="true"} 1`) {
+		t.Errorf("unexpected textfile:\n%s", data)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("temporary file should be renamed into place")
+	}
+}
+
+func TestPushProm(t *testing.T) {
+	var method, path, body string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(data)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gateway.Close()
+
+	tracker := trackLines(t, []int{0, 1, 2, 4, 6, 7}, subagentSessionLines)
+	streams := []*stream{{config: &FilterConfig{Tracker: tracker}}}
+
+	if err := pushProm(gateway.URL, streams); err != nil {
+		t.Fatalf("pushProm() error = %v", err)
+	}
+	if method != http.MethodPut || path != "/metrics/job/ccfilter" {
+		t.Errorf("request = %s %s, want PUT /metrics/job/ccfilter", method, path)
+	}
+	if !strings.Contains(body, "ccfilter_sessions") {
+		t.Errorf("pushed body = %q", body)
+	}
+
+	if err := pushProm(gateway.URL+"/metrics/job/agents/instance/runner1", streams); err != nil {
+		t.Fatalf("pushProm() error = %v", err)
+	}
+	if path != "/metrics/job/agents/instance/runner1" {
+		t.Errorf("custom grouping path = %s", path)
+	}
+}
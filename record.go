@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Envelope は --record で記録する1行分のデータ (到着時刻付き)
+type Envelope struct {
+	Time time.Time       `json:"ts"`
+	Line json.RawMessage `json:"line"`
+}
+
+// parseEnvelope は記録済みのエンベロープ行を展開
+// エンベロープでない行 (通常の stream-json) の場合は ok=false を返す
+func parseEnvelope(line string) (Envelope, bool) {
+	if !strings.HasPrefix(line, `{"ts":`) {
+		return Envelope{}, false
+	}
+
+	var env Envelope
+	if err := json.Unmarshal([]byte(line), &env); err != nil {
+		return Envelope{}, false
+	}
+	if env.Time.IsZero() || len(env.Line) == 0 {
+		return Envelope{}, false
+	}
+	return env, true
+}
+
+// recorder は入力行を到着時刻付きのエンベロープ形式で書き出す
+type recorder struct {
+	enc *json.Encoder
+}
+
+// newRecorder は w に書き出す recorder を作成
+func newRecorder(w io.Writer) *recorder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &recorder{enc: enc}
+}
+
+// record は1行を記録 (空行と不正なJSONは記録しない)
+func (r *recorder) record(at time.Time, line string) error {
+	if line == "" || !json.Valid([]byte(line)) {
+		return nil
+	}
+	return r.enc.Encode(Envelope{Time: at, Line: json.RawMessage(line)})
+}
+
+// pacer は記録時の間隔を再現するために待機する
+type pacer struct {
+	speed float64 // 再生速度 (0 の場合は待機しない)
+	sleep func(time.Duration)
+	last  time.Time
+}
+
+// newPacer は指定速度で待機する pacer を作成
+func newPacer(speed float64, sleep func(time.Duration)) *pacer {
+	return &pacer{speed: speed, sleep: sleep}
+}
+
+// wait は前の行との時刻差を速度で割った時間だけ待機
+func (p *pacer) wait(at time.Time) {
+	if p == nil || p.speed <= 0 {
+		return
+	}
+	if !p.last.IsZero() && at.After(p.last) {
+		p.sleep(time.Duration(float64(at.Sub(p.last)) / p.speed))
+	}
+	p.last = at
+}
+
+// parseSpeed は "4x", "0.5", "instant" 形式の再生速度をパース (instant は 0)
+func parseSpeed(s string) (float64, error) {
+	if s == "instant" {
+		return 0, nil
+	}
+
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+	if err != nil || speed < 0 {
+		return 0, fmt.Errorf("invalid speed: %s (must be like 4x, 0.5x or instant)", s)
+	}
+	return speed, nil
+}
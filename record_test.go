@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEnvelope(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		wantOK   bool
+		wantLine string
+	}{
+		{
+			name:     "envelope",
+			line:     `{"ts":"2025-10-18T10:00:00Z","line":{"type":"assistant"}}`,
+			wantOK:   true,
+			wantLine: `{"type":"assistant"}`,
+		},
+		{
+			name:   "plain stream-json line",
+			line:   `{"type":"assistant","message":{"content":[]}}`,
+			wantOK: false,
+		},
+		{
+			name:   "missing line",
+			line:   `{"ts":"2025-10-18T10:00:00Z"}`,
+			wantOK: false,
+		},
+		{
+			name:   "invalid json",
+			line:   `{"ts":invalid}`,
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, ok := parseEnvelope(tt.line)
+			if ok != tt.wantOK {
+				t.Fatalf("parseEnvelope() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && string(env.Line) != tt.wantLine {
+				t.Errorf("parseEnvelope() line = %s, want %s", env.Line, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	rec := newRecorder(&buf)
+	at := time.Date(2025, 10, 18, 10, 0, 0, 0, time.UTC)
+
+	lines := []string{
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"<b>"}]}}`,
+		"",
+		"{invalid}",
+	}
+	for _, line := range lines {
+		if err := rec.record(at, line); err != nil {
+			t.Fatalf("record() error = %v", err)
+		}
+	}
+
+	want := `{"ts":"2025-10-18T10:00:00Z","line":{"type":"assistant","message":{"content":[{"type":"text","text":"<b>"}]}}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("record() wrote %q, want %q", got, want)
+	}
+
+	env, ok := parseEnvelope(strings.TrimSpace(buf.String()))
+	if !ok || !env.Time.Equal(at) || string(env.Line) != lines[0] {
+		t.Errorf("recorded envelope does not round-trip: %+v", env)
+	}
+}
+
+func TestPacer(t *testing.T) {
+	base := time.Date(2025, 10, 18, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		speed float64
+		times []time.Time
+		want  []time.Duration
+	}{
+		{
+			name:  "original pacing",
+			speed: 1,
+			times: []time.Time{base, base.Add(2 * time.Second), base.Add(5 * time.Second)},
+			want:  []time.Duration{2 * time.Second, 3 * time.Second},
+		},
+		{
+			name:  "4x",
+			speed: 4,
+			times: []time.Time{base, base.Add(2 * time.Second)},
+			want:  []time.Duration{500 * time.Millisecond},
+		},
+		{
+			name:  "instant",
+			speed: 0,
+			times: []time.Time{base, base.Add(2 * time.Second)},
+			want:  nil,
+		},
+		{
+			name:  "out of order timestamps do not wait",
+			speed: 1,
+			times: []time.Time{base.Add(time.Second), base},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []time.Duration
+			p := newPacer(tt.speed, func(d time.Duration) { got = append(got, d) })
+			for _, at := range tt.times {
+				p.wait(at)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("sleeps = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("sleep[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseSpeed(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{input: "4x", want: 4},
+		{input: "0.5x", want: 0.5},
+		{input: "2", want: 2},
+		{input: "instant", want: 0},
+		{input: "fast", wantErr: true},
+		{input: "-1x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseSpeed(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSpeed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseSpeed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	recordPath := filepath.Join(t.TempDir(), "session.jsonl")
+	input := `{"type":"assistant","message":{"content":[{"type":"text","text":"Hello"}]}}
+{"type":"result","subtype":"success","result":"Done","duration_ms":1000,"total_cost_usd":0.01,"num_turns":1}`
+
+	config := NewFilterConfig()
+	config.UseColor = false
+	config.RecordPath = recordPath
+
+	var live bytes.Buffer
+	if err := processInput(strings.NewReader(input), &live, config); err != nil {
+		t.Fatalf("processInput() error = %v", err)
+	}
+
+	recorded, err := os.ReadFile(recordPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(recorded)), "\n") {
+		if _, ok := parseEnvelope(line); !ok {
+			t.Errorf("recorded line is not an envelope: %s", line)
+		}
+	}
+
+	replayConfig := NewFilterConfig()
+	replayConfig.UseColor = false
+	replayConfig.Command = "replay"
+	replayConfig.ReplaySpeed = 0
+
+	var replayed bytes.Buffer
+	if err := processInputs([]InputSource{{Path: recordPath}}, &replayed, replayConfig); err != nil {
+		t.Fatalf("processInputs() error = %v", err)
+	}
+	if replayed.String() != live.String() {
+		t.Errorf("replay output = %q, want %q", replayed.String(), live.String())
+	}
+}
+
+func TestRecord_Redacted(t *testing.T) {
+	recordPath := filepath.Join(t.TempDir(), "session.jsonl")
+	input := `{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"GITHUB_TOKEN=` + fakeGitHubToken + `"}]},"session_id":"s1"}` + "\n"
+
+	redactor, err := newRedactor(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := NewFilterConfig()
+	config.UseColor = false
+	config.RecordPath = recordPath
+	config.Redactor = redactor
+	config.Anonymizer = newAnonymizer(false)
+
+	if err := processInput(strings.NewReader(input), io.Discard, config); err != nil {
+		t.Fatalf("processInput() error = %v", err)
+	}
+
+	recorded, err := os.ReadFile(recordPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(recorded)
+	if strings.Contains(got, fakeGitHubToken) || strings.Contains(got, `"s1"`) {
+		t.Errorf("record leaks the original line: %s", got)
+	}
+	if !strings.Contains(got, "[REDACTED:github-token#1]") {
+		t.Errorf("record does not contain the redacted line: %s", got)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redactRule は秘密情報の検出ルール
+// パターンに名前付きグループ "secret" がある場合はその部分だけを伏せる
+type redactRule struct {
+	name     string
+	pattern  *regexp.Regexp
+	keywords []string // マッチする文字列が必ず含む語 (小文字)。どれも含まない文字列には正規表現を使わない
+}
+
+// builtinRedactRules は組み込みの検出ルール (上から順に適用する)
+var builtinRedactRules = []redactRule{
+	{"private-key", regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`), []string{"private key-----"}},
+	{"anthropic-api-key", regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`), []string{"sk-ant-"}},
+	{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`), []string{"ghp_", "gho_", "ghu_", "ghs_", "ghr_", "github_pat_"}},
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[A-Z0-9]{16}\b`), []string{"akia", "asia", "agpa", "aida", "aroa", "anpa", "anva", "aipa"}},
+	{"aws-secret-key", regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[=:]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})\b`), []string{"access"}},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{10,}`), []string{"eyj"}},
+	{"password", regexp.MustCompile(`(?i)\b\w{0,32}(?:password|passwd)\w{0,32}["']?\s*[=:]\s*(?P<secret>"[^"\n]+"|'[^'\n]+'|[^\s"'&;,)]+)`), []string{"password", "passwd"}},
+}
+
+// mayMatch は文字列 lower (小文字にしたもの) がルールにマッチしうるかどうかを判定
+// キーワードのないルール (ユーザー定義のパターン) は常に正規表現で調べる
+func (rule redactRule) mayMatch(lower string) bool {
+	if len(rule.keywords) == 0 {
+		return true
+	}
+	for _, keyword := range rule.keywords {
+		if strings.Contains(lower, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactNamePattern はユーザー定義のルール名に使える文字
+var redactNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// redactor は秘密情報をプレースホルダーに置き換える
+// 同じ秘密情報には常に同じプレースホルダー ([REDACTED:github-token#1] など) を使うので、
+// 伏せた後でも同じ値が繰り返し出てきたことがわかる
+type redactor struct {
+	rules []redactRule
+
+	mu     sync.Mutex
+	seen   map[string]string // ルール名と秘密情報 → プレースホルダー
+	counts map[string]int    // ルールごとの秘密情報の数
+}
+
+// newRedactor は組み込みのルールとユーザー定義のパターン (名前 → 正規表現) から redactor を作成
+func newRedactor(patterns map[string]string) (*redactor, error) {
+	r := &redactor{
+		rules:  append([]redactRule{}, builtinRedactRules...),
+		seen:   make(map[string]string),
+		counts: make(map[string]int),
+	}
+
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !redactNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid redact pattern name: %q (use lowercase letters, digits and -)", name)
+		}
+		re, err := regexp.Compile(patterns[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %s: %w", name, err)
+		}
+		r.rules = append(r.rules, redactRule{name: name, pattern: re})
+	}
+	return r, nil
+}
+
+// redact は文字列中の秘密情報を置き換える
+func (r *redactor) redact(s string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lower := strings.ToLower(s)
+	for _, rule := range r.rules {
+		if !rule.mayMatch(lower) {
+			continue
+		}
+		group := rule.pattern.SubexpIndex("secret")
+		s = replaceAllSubmatchFunc(rule.pattern, s, func(m []int) (int, int) {
+			if group > 0 && m[2*group] >= 0 {
+				return m[2*group], m[2*group+1]
+			}
+			return m[0], m[1]
+		}, func(secret string) string {
+			// 先に適用したルールで伏せた部分は番号を保つためそのままにする
+			if strings.Contains(secret, "[REDACTED:") {
+				return secret
+			}
+			return r.placeholder(rule.name, secret)
+		})
+	}
+	return s
+}
+
+// placeholder は秘密情報に対応するプレースホルダーを返す (初めての値には次の番号を割り当てる)
+func (r *redactor) placeholder(rule, secret string) string {
+	key := rule + "\x00" + secret
+	if p, ok := r.seen[key]; ok {
+		return p
+	}
+	r.counts[rule]++
+	p := fmt.Sprintf("[REDACTED:%s#%d]", rule, r.counts[rule])
+	r.seen[key] = p
+	return p
+}
+
+// replaceAllSubmatchFunc はマッチごとに span で選んだ範囲を replace の結果に置き換える
+func replaceAllSubmatchFunc(re *regexp.Regexp, s string, span func(m []int) (int, int), replace func(string) string) string {
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+
+	var output strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := span(m)
+		output.WriteString(s[last:start])
+		output.WriteString(replace(s[start:end]))
+		last = end
+	}
+	output.WriteString(s[last:])
+	return output.String()
+}
+
+// redactLine は JSON 行のすべての文字列の値から秘密情報を伏せる
+// (テキスト・Markdown・各種レポートやエクスポートのすべてに反映されるよう、入力の時点で置き換える)
+// 伏せる値がない場合や JSON として解析できない場合は元の行を返す
+func (r *redactor) redactLine(line string) string {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return line
+	}
+
+	changed := false
+	value = r.redactValue(value, &changed)
+	if !changed {
+		return line
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return line
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// redactValue は JSON の値の中の文字列を再帰的に置き換える
+func (r *redactor) redactValue(value interface{}, changed *bool) interface{} {
+	switch v := value.(type) {
+	case string:
+		redacted := r.redact(v)
+		if redacted != v {
+			*changed = true
+		}
+		return redacted
+	case []interface{}:
+		for i := range v {
+			v[i] = r.redactValue(v[i], changed)
+		}
+	case map[string]interface{}:
+		// プレースホルダーの番号が実行ごとに変わらないよう、キーの順に処理する
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			v[key] = r.redactValue(v[key], changed)
+		}
+	}
+	return value
+}
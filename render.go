@@ -0,0 +1,157 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ANSI のスタイル (色を打ち消さないよう、解除には個別のコードを使う)
+const (
+	StyleBold          = "\x1b[1m"
+	StyleBoldOff       = "\x1b[22m" // 太字と dim の両方を解除する
+	StyleDim           = "\x1b[2m"
+	StyleItalic        = "\x1b[3m"
+	StyleItalicOff     = "\x1b[23m"
+	StyleUnderline     = "\x1b[4m"
+	StyleUnderlineOff  = "\x1b[24m"
+	StyleStrike        = "\x1b[9m"
+	StyleStrikeOff     = "\x1b[29m"
+	StyleForegroundOff = "\x1b[39m"
+	StyleBackgroundOff = "\x1b[49m"
+)
+
+// codeBlockWidth はコードブロックの枠の横線の長さ
+const codeBlockWidth = 40
+
+var (
+	headingPattern  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	fencePattern    = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([^`\\s]*)")
+	quotePattern    = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	bulletPattern   = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedPattern  = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	rulePattern     = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	taskPattern     = regexp.MustCompile(`^\[([ xX])\]\s+(.*)$`)
+	codeSpanPattern = regexp.MustCompile("`+[^`]+`+")
+	linkPattern     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	boldPattern     = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicPattern   = regexp.MustCompile(`(^|[^*\w])\*([^*\s][^*]*)\*|(^|[^_\w])_([^_\s][^_]*)_($|\W)`)
+	strikePattern   = regexp.MustCompile(`~~([^~]+)~~`)
+)
+
+// renderMarkdown は assistant のテキストの Markdown を ANSI のスタイルで装飾
+// カラー出力が無効な場合は元のテキストをそのまま返す
+func renderMarkdown(text string, colors *palette) string {
+	if colors == nil {
+		return text
+	}
+
+	var output []string
+	var fence, lang string
+	var code []string
+	for _, line := range strings.Split(text, "\n") {
+		// コードブロックの中はそのまま枠で囲む
+		if fence != "" {
+			if isClosingFence(line, fence) {
+				output = append(output, codeBlock(lang, code, colors)...)
+				fence, code = "", nil
+				continue
+			}
+			code = append(code, line)
+			continue
+		}
+		if m := fencePattern.FindStringSubmatch(line); m != nil {
+			fence, lang = m[1], m[2]
+			continue
+		}
+		output = append(output, renderLine(line, colors))
+	}
+	// 閉じられていないコードブロック (ストリームの途中など)
+	if fence != "" {
+		output = append(output, codeBlock(lang, code, colors)...)
+	}
+
+	return strings.Join(output, "\n")
+}
+
+// isClosingFence は line がコードブロックを閉じる行かどうかを判定
+// CommonMark に従い、開始と同じ種類の記号が開始以上の数だけ並び、その後に空白しかない行とする
+// (```go のように情報文字列が続く行はコードブロックの中身)
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return false
+	}
+	rest := strings.TrimLeft(trimmed, fence[:1])
+	return len(trimmed)-len(rest) >= len(fence) && strings.TrimSpace(rest) == ""
+}
+
+// renderLine はブロック要素1行を装飾
+func renderLine(line string, colors *palette) string {
+	if m := headingPattern.FindStringSubmatch(line); m != nil {
+		if len(m[1]) <= 2 {
+			return colors.open(roleHeading) + renderInline(m[2], colors) + colors.close(roleHeading)
+		}
+		return StyleBold + renderInline(m[2], colors) + StyleBoldOff
+	}
+	if rulePattern.MatchString(line) {
+		return colorize(strings.Repeat("─", codeBlockWidth), roleMuted, colors)
+	}
+	if m := quotePattern.FindStringSubmatch(line); m != nil {
+		return colorize("│", roleMuted, colors) + " " + StyleItalic + renderInline(m[1], colors) + StyleItalicOff
+	}
+	if m := bulletPattern.FindStringSubmatch(line); m != nil {
+		marker, item := colorize("•", roleBullet, colors), m[2]
+		if t := taskPattern.FindStringSubmatch(item); t != nil {
+			marker, item = "☐", t[2]
+			if t[1] != " " {
+				marker = colorize("☑", roleSuccess, colors)
+			}
+		}
+		return m[1] + marker + " " + renderInline(item, colors)
+	}
+	if m := orderedPattern.FindStringSubmatch(line); m != nil {
+		return m[1] + colorize(m[2], roleBullet, colors) + " " + renderInline(m[3], colors)
+	}
+	return renderInline(line, colors)
+}
+
+// renderInline はインラインコード以外の部分の強調・リンク・打ち消し線を装飾
+func renderInline(text string, colors *palette) string {
+	var output strings.Builder
+	last := 0
+	for _, loc := range codeSpanPattern.FindAllStringIndex(text, -1) {
+		output.WriteString(renderEmphasis(text[last:loc[0]], colors))
+		span := strings.Trim(text[loc[0]:loc[1]], "`")
+		// 見出しや引用の中でもスタイルが解除されないよう、リセットせずに個別に解除する
+		output.WriteString(colors.open(roleCode) + span + colors.close(roleCode))
+		last = loc[1]
+	}
+	output.WriteString(renderEmphasis(text[last:], colors))
+	return output.String()
+}
+
+// renderEmphasis はコードを含まないテキストを装飾
+func renderEmphasis(text string, colors *palette) string {
+	text = linkPattern.ReplaceAllString(text, StyleUnderline+"$1"+StyleUnderlineOff+" "+colors.open(roleLink)+"($2)"+colors.close(roleLink))
+	text = boldPattern.ReplaceAllString(text, StyleBold+"$1$2"+StyleBoldOff)
+	text = italicPattern.ReplaceAllString(text, "$1$3"+StyleItalic+"$2$4"+StyleItalicOff+"$5")
+	text = strikePattern.ReplaceAllString(text, StyleStrike+"$1"+StyleStrikeOff)
+	return text
+}
+
+// codeBlock はコードブロックを枠で囲む
+func codeBlock(lang string, lines []string, colors *palette) []string {
+	top := "┌─"
+	if lang != "" {
+		top += " " + lang + " "
+	}
+	top += strings.Repeat("─", max(codeBlockWidth-len([]rune(top)), 0))
+
+	output := []string{colorize(top, roleMuted, colors)}
+	h := newHighlighter(detectLanguage(lang), colors)
+	for _, line := range lines {
+		output = append(output, colorize("│", roleMuted, colors)+" "+h.line(line))
+	}
+	output = append(output, colorize("└"+strings.Repeat("─", codeBlockWidth-1), roleMuted, colors))
+	return output
+}
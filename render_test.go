@@ -0,0 +1,145 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "heading",
+			input: "## Summary",
+			want:  StyleBold + ColorCyan + "Summary" + StyleForegroundOff + StyleBoldOff,
+		},
+		{
+			name:  "minor heading",
+			input: "### Details ###",
+			want:  StyleBold + "Details" + StyleBoldOff,
+		},
+		{
+			name:  "emphasis and inline code",
+			input: "Fix **now** in *main.go* with `go test` and ~~not~~ that",
+			want:  "Fix " + StyleBold + "now" + StyleBoldOff + " in " + StyleItalic + "main.go" + StyleItalicOff + " with " + ColorYellow + "go test" + StyleForegroundOff + " and " + StyleStrike + "not" + StyleStrikeOff + " that",
+		},
+		{
+			name:  "markup inside inline code is kept",
+			input: "run `a **b** c`",
+			want:  "run " + ColorYellow + "a **b** c" + StyleForegroundOff,
+		},
+		{
+			name:  "snake_case is not italic",
+			input: "call parse_args_now",
+			want:  "call parse_args_now",
+		},
+		{
+			name:  "link",
+			input: "see [docs](https://example.com)",
+			want:  "see " + StyleUnderline + "docs" + StyleUnderlineOff + " " + ColorGray + "(https://example.com)" + StyleForegroundOff,
+		},
+		{
+			name:  "lists",
+			input: "- one\n  2. two\n- [x] done",
+			want:  ColorCyan + "•" + ColorReset + " one\n  " + ColorCyan + "2." + ColorReset + " two\n" + ColorGreen + "☑" + ColorReset + " done",
+		},
+		{
+			name:  "block quote",
+			input: "> careful",
+			want:  ColorGray + "│" + ColorReset + " " + StyleItalic + "careful" + StyleItalicOff,
+		},
+		{
+			name:  "horizontal rule",
+			input: "***",
+			want:  ColorGray + strings.Repeat("─", codeBlockWidth) + ColorReset,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderMarkdown(tt.input, defaultColors); got != tt.want {
+				t.Errorf("renderMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdown_CodeBlock(t *testing.T) {
+	input := "Before\n```go\nx := **y**\n# not a heading\n```\nAfter"
+
+	got := strings.Split(renderMarkdown(input, defaultColors), "\n")
+	want := []string{
+		"Before",
+		ColorGray + "┌─ go " + strings.Repeat("─", codeBlockWidth-6) + ColorReset,
+		ColorGray + "│" + ColorReset + " x := **y**",
+		ColorGray + "│" + ColorReset + " # not a heading",
+		ColorGray + "└" + strings.Repeat("─", codeBlockWidth-1) + ColorReset,
+		"After",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("renderMarkdown() =\n%s", strings.Join(got, "\n"))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRenderMarkdown_NoColor(t *testing.T) {
+	input := "# Title\n\n- **bold** `code`\n```\nx\n```"
+	if got := renderMarkdown(input, nil); got != input {
+		t.Errorf("renderMarkdown() without color = %q, want the text unchanged", got)
+	}
+}
+
+func TestIsClosingFence(t *testing.T) {
+	tests := []struct {
+		line  string
+		fence string
+		want  bool
+	}{
+		{line: "```", fence: "```", want: true},
+		{line: "```  ", fence: "```", want: true},
+		{line: "   ```", fence: "```", want: true},
+		{line: "`````", fence: "```", want: true},
+		{line: "```go", fence: "```", want: false},
+		{line: "``` x", fence: "```", want: false},
+		{line: "```", fence: "````", want: false},
+		{line: "~~~", fence: "```", want: false},
+		{line: "    ```", fence: "```", want: false},
+		{line: "~~~", fence: "~~~", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := isClosingFence(tt.line, tt.fence); got != tt.want {
+			t.Errorf("isClosingFence(%q, %q) = %v, want %v", tt.line, tt.fence, got, tt.want)
+		}
+	}
+}
+
+func TestRenderMarkdown_NestedFence(t *testing.T) {
+	// 4つの ` で開いたブロックの中の ```go は中身として表示する
+	input := "````markdown\n```go\nfmt.Println()\n```\n````\nAfter"
+
+	got := strings.Split(renderMarkdown(input, defaultColors), "\n")
+	want := []string{
+		ColorGray + "┌─ markdown " + strings.Repeat("─", codeBlockWidth-12) + ColorReset,
+		ColorGray + "│" + ColorReset + " ```go",
+		ColorGray + "│" + ColorReset + " fmt.Println()",
+		ColorGray + "│" + ColorReset + " ```",
+		ColorGray + "└" + strings.Repeat("─", codeBlockWidth-1) + ColorReset,
+		"After",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("renderMarkdown() =\n%s", strings.Join(got, "\n"))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
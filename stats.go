@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Stats はセッション全体の統計情報
+type Stats struct {
+	SessionID         string                `json:"session_id,omitempty"`
+	Turns             int                   `json:"turns"`
+	ToolCalls         map[string]int        `json:"tool_calls"`
+	ToolErrors        map[string]int        `json:"tool_errors"`
+	Errors            int                   `json:"errors"`
+	PermissionDenials int                   `json:"permission_denials"`
+	Tokens            Usage                 `json:"tokens"`
+	Models            map[string]ModelUsage `json:"models"`
+	CostUsd           float64               `json:"cost_usd"`
+	DurationMs        int                   `json:"duration_ms"`
+	FilesRead         []string              `json:"files_read"`
+	FilesWritten      []string              `json:"files_written"`
+	FilesEdited       []string              `json:"files_edited"`
+	BashCommands      []string              `json:"bash_commands"`
+}
+
+// computeStats は Tracker に記録された内容から統計情報を集計
+func computeStats(t *Tracker) Stats {
+	stats := Stats{
+		ToolCalls:    make(map[string]int),
+		ToolErrors:   make(map[string]int),
+		Models:       make(map[string]ModelUsage),
+		FilesRead:    []string{},
+		FilesWritten: []string{},
+		FilesEdited:  []string{},
+		BashCommands: []string{},
+	}
+
+	seen := make(map[string]bool)
+	addOnce := func(list *[]string, kind, value string) {
+		if value == "" || seen[kind+"\x00"+value] {
+			return
+		}
+		seen[kind+"\x00"+value] = true
+		*list = append(*list, value)
+	}
+
+	for _, step := range t.Steps {
+		switch step.Kind {
+		case "turn":
+			stats.Turns++
+			model := stats.Models[step.Name]
+			model.InputTokens += step.Usage.InputTokens
+			model.OutputTokens += step.Usage.OutputTokens
+			model.CacheReadInputTokens += step.Usage.CacheReadInputTokens
+			model.CacheCreationInputTokens += step.Usage.CacheCreationInputTokens
+			stats.Models[step.Name] = model
+		case "tool":
+			stats.ToolCalls[step.Name]++
+			if step.IsError {
+				stats.ToolErrors[step.Name]++
+				stats.Errors++
+			}
+
+			var input map[string]interface{}
+			if err := json.Unmarshal(step.Input, &input); err != nil {
+				continue
+			}
+			path, _ := input["file_path"].(string)
+			switch step.Name {
+			case "Read":
+				addOnce(&stats.FilesRead, "read", path)
+			case "Write":
+				addOnce(&stats.FilesWritten, "write", path)
+			case "Edit", "MultiEdit":
+				addOnce(&stats.FilesEdited, "edit", path)
+			case "NotebookEdit":
+				notebook, _ := input["notebook_path"].(string)
+				addOnce(&stats.FilesEdited, "edit", notebook)
+			case "Bash":
+				command, _ := input["command"].(string)
+				addOnce(&stats.BashCommands, "bash", command)
+			}
+		}
+	}
+
+	stats.SessionID = t.SessionID()
+
+	// result の modelUsage はサブエージェント分も含むため、あればそちらを優先
+	if r := t.Result; r != nil {
+		stats.CostUsd = r.TotalCostUsd
+		stats.DurationMs = r.DurationMs
+		stats.PermissionDenials = len(r.PermissionDenials)
+		if len(r.ModelUsage) > 0 {
+			stats.Models = r.ModelUsage
+		}
+	}
+	delete(stats.Models, "")
+
+	for _, model := range stats.Models {
+		stats.Tokens.InputTokens += model.InputTokens
+		stats.Tokens.OutputTokens += model.OutputTokens
+		stats.Tokens.CacheReadInputTokens += model.CacheReadInputTokens
+		stats.Tokens.CacheCreationInputTokens += model.CacheCreationInputTokens
+	}
+
+	return stats
+}
+
+// formatStats は統計情報を result ブロックの後に表示する形式でフォーマット
+func formatStats(stats Stats, config *FilterConfig) string {
+	var output strings.Builder
+
+	output.WriteString("\n")
+	output.WriteString(colorize("Stats:", roleMetrics, config.colors()))
+	output.WriteString("\n")
+
+	toolCalls := 0
+	for _, n := range stats.ToolCalls {
+		toolCalls += n
+	}
+	output.WriteString(fmt.Sprintf("  Turns: %d | Tool calls: %d | Errors: %d | Permission denials: %d\n",
+		stats.Turns, toolCalls, stats.Errors, stats.PermissionDenials))
+
+	if len(stats.ToolCalls) > 0 {
+		var tools []string
+		for _, name := range sortedKeys(stats.ToolCalls) {
+			tool := fmt.Sprintf("%s ×%d", colorize(sanitizeText(name, config), roleTool, config.colors()), stats.ToolCalls[name])
+			if n := stats.ToolErrors[name]; n > 0 {
+				tool += " " + colorize(fmt.Sprintf("(errors: %d)", n), roleError, config.colors())
+			}
+			tools = append(tools, tool)
+		}
+		output.WriteString("  Tools: " + strings.Join(tools, ", ") + "\n")
+	}
+
+	output.WriteString("  Tokens: " + formatTokens(stats.Tokens) + "\n")
+	for _, name := range sortedKeys(stats.Models) {
+		model := stats.Models[name]
+		tokens := formatTokens(Usage{
+			InputTokens:              model.InputTokens,
+			OutputTokens:             model.OutputTokens,
+			CacheReadInputTokens:     model.CacheReadInputTokens,
+			CacheCreationInputTokens: model.CacheCreationInputTokens,
+		})
+		line := fmt.Sprintf("    %s: %s", sanitizeText(name, config), tokens)
+		if model.CostUSD > 0 {
+			line += fmt.Sprintf(" | $%.4f", model.CostUSD)
+		}
+		output.WriteString(line + "\n")
+	}
+	output.WriteString(fmt.Sprintf("  Cost: $%.4f\n", stats.CostUsd))
+
+	// ファイルのパスやコマンドはツールの入力そのままなので、他の表示と同様に無害化する
+	writeList := func(label string, items []string) {
+		if len(items) > 0 {
+			output.WriteString(fmt.Sprintf("  %s: %s\n", label, sanitizeText(strings.Join(items, ", "), config)))
+		}
+	}
+	writeList("Files read", stats.FilesRead)
+	writeList("Files written", stats.FilesWritten)
+	writeList("Files edited", stats.FilesEdited)
+	if len(stats.BashCommands) > 0 {
+		output.WriteString("  Bash commands:\n")
+		for _, command := range stats.BashCommands {
+			output.WriteString("    " + sanitizeText(firstLine(command), config) + "\n")
+		}
+	}
+
+	return output.String()
+}
+
+// formatTokens はトークン使用量を1行にフォーマット
+func formatTokens(u Usage) string {
+	return fmt.Sprintf("input %d | output %d | cache read %d | cache write %d",
+		u.InputTokens, u.OutputTokens, u.CacheReadInputTokens, u.CacheCreationInputTokens)
+}
+
+// writeStatsJSON は各セッションの統計情報を1行1オブジェクトのJSONで書き出す
+func writeStatsJSON(path string, streams []*stream) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create stats file: %w", err)
+	}
+	defer f.Close()
+
+	return encodeStats(f, streams)
+}
+
+// encodeStats は各セッションの統計情報を w に書き出す
+func encodeStats(w io.Writer, streams []*stream) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, s := range streams {
+		if err := enc.Encode(computeStats(s.config.Tracker)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedKeys はマップのキーをソートして返す
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// firstLine は複数行の文字列の1行目を返す
+func firstLine(s string) string {
+	line, _, found := strings.Cut(s, "\n")
+	if found {
+		return line + " ..."
+	}
+	return line
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// trackFile は testdata のファイルを Tracker に記録する
+func trackFile(t *testing.T, path string) *Tracker {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	return trackLines(t, make([]int, len(lines)), lines)
+}
+
+func TestComputeStats_PermissionDenied(t *testing.T) {
+	stats := computeStats(trackFile(t, "testdata/permission_denied.json"))
+
+	if stats.SessionID != "ef076ce9-9d77-43cd-895c-c7686b45a9a0" {
+		t.Errorf("SessionID = %v", stats.SessionID)
+	}
+	if stats.Turns != 2 {
+		t.Errorf("Turns = %d, want 2", stats.Turns)
+	}
+	if stats.ToolCalls["Write"] != 1 || stats.ToolErrors["Write"] != 1 || stats.Errors != 1 {
+		t.Errorf("ToolCalls = %v, ToolErrors = %v, Errors = %d", stats.ToolCalls, stats.ToolErrors, stats.Errors)
+	}
+	if stats.PermissionDenials != 1 {
+		t.Errorf("PermissionDenials = %d, want 1", stats.PermissionDenials)
+	}
+	if len(stats.Models) != 2 || stats.Models["claude-haiku-4-5-20251001"].OutputTokens != 379 {
+		t.Errorf("Models = %+v", stats.Models)
+	}
+	if stats.Tokens.InputTokens != 825 || stats.Tokens.OutputTokens != 622 {
+		t.Errorf("Tokens = %+v, want input 825 / output 622", stats.Tokens)
+	}
+	if stats.CostUsd != 0.017985349999999997 || stats.DurationMs != 7708 {
+		t.Errorf("CostUsd = %v, DurationMs = %v", stats.CostUsd, stats.DurationMs)
+	}
+	if len(stats.FilesWritten) != 1 || !strings.HasSuffix(stats.FilesWritten[0], "hello.go") {
+		t.Errorf("FilesWritten = %v", stats.FilesWritten)
+	}
+}
+
+func TestComputeStats_FromTurns(t *testing.T) {
+	tracker := trackLines(t, []int{0, 1, 1, 2, 3, 4, 5}, []string{
+		`{"type":"assistant","message":{"id":"m1","model":"sonnet","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go test ./..."}}],"usage":{"input_tokens":10,"output_tokens":1}}}`,
+		`{"type":"assistant","message":{"id":"m1","model":"sonnet","content":[{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"a.go"}}],"usage":{"input_tokens":10,"output_tokens":20}}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"},{"type":"tool_result","tool_use_id":"t2","content":"package a"}]}}`,
+		`{"type":"assistant","message":{"id":"m2","model":"sonnet","content":[{"type":"tool_use","id":"t3","name":"Bash","input":{"command":"go test ./..."}}],"usage":{"input_tokens":5,"output_tokens":3}}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t3","content":"FAIL","is_error":true}]}}`,
+		`{"type":"assistant","message":{"id":"m3","model":"sonnet","content":[{"type":"tool_use","id":"t4","name":"Edit","input":{"file_path":"a.go"}}],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t4","content":"ok"}]}}`,
+	})
+
+	stats := computeStats(tracker)
+
+	if stats.Turns != 3 {
+		t.Errorf("Turns = %d, want 3", stats.Turns)
+	}
+	if stats.ToolCalls["Bash"] != 2 || stats.ToolErrors["Bash"] != 1 {
+		t.Errorf("ToolCalls = %v, ToolErrors = %v", stats.ToolCalls, stats.ToolErrors)
+	}
+	// 同じ message id のチャンクは最後の usage のみ数える
+	if got := stats.Models["sonnet"]; got.InputTokens != 16 || got.OutputTokens != 24 {
+		t.Errorf("Models[sonnet] = %+v, want input 16 / output 24", got)
+	}
+	if len(stats.BashCommands) != 1 || stats.BashCommands[0] != "go test ./..." {
+		t.Errorf("BashCommands = %v", stats.BashCommands)
+	}
+	if len(stats.FilesRead) != 1 || len(stats.FilesEdited) != 1 {
+		t.Errorf("FilesRead = %v, FilesEdited = %v", stats.FilesRead, stats.FilesEdited)
+	}
+}
+
+func TestFormatStats(t *testing.T) {
+	stats := computeStats(trackFile(t, "testdata/permission_denied.json"))
+	got := formatStats(stats, &FilterConfig{UseColor: false})
+
+	for _, want := range []string{
+		"Stats:",
+		"Turns: 2 | Tool calls: 1 | Errors: 1 | Permission denials: 1",
+		"Tools: Write ×1 (errors: 1)",
+		"Tokens: input 825 | output 622 | cache read 29617 | cache write 723",
+		"claude-sonnet-4-5-20250929: input 12 | output 243 | cache read 29617 | cache write 723 | $0.0153",
+		"Cost: $0.0180",
+		"Files written: /home/pankona/go/src/github.com/pankona/ccfilter/hello.go",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("formatStats() does not contain %q\nGot: %s", want, got)
+		}
+	}
+}
+
+func TestWriteStatsJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	config := NewFilterConfig()
+	config.UseColor = false
+	config.StatsJSONPath = path
+
+	input, err := os.ReadFile("testdata/permission_denied.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	if err := processInput(bytes.NewReader(input), &output, config); err != nil {
+		t.Fatalf("processInput() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats Stats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		t.Fatalf("stats file is not valid JSON: %v\n%s", err, data)
+	}
+	if stats.ToolCalls["Write"] != 1 || stats.PermissionDenials != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestProcessInput_StatsOnlyInText(t *testing.T) {
+	data, err := os.ReadFile("testdata/permission_denied.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"text", "json", "markdown", "stream-json"} {
+		t.Run(format, func(t *testing.T) {
+			config := NewFilterConfig()
+			config.UseColor = false
+			config.Format = format
+			config.ShowStats = true
+			config.ShowTimeline = true
+
+			var output bytes.Buffer
+			if err := processInput(bytes.NewReader(data), &output, config); err != nil {
+				t.Fatalf("processInput() error = %v", err)
+			}
+			got := output.String()
+
+			if hasStats := strings.Contains(got, "Stats:"); hasStats != (format == "text") {
+				t.Errorf("%s output has stats = %v:\n%s", format, hasStats, got)
+			}
+			if format != "json" && format != "stream-json" {
+				return
+			}
+			// JSON の出力はすべての行が JSON として読める
+			for _, line := range strings.Split(strings.TrimSpace(got), "\n") {
+				if !json.Valid([]byte(line)) {
+					t.Errorf("invalid JSON line: %s", line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// stream は1つの入力ストリームの処理状態を保持する
+type stream struct {
+	output io.Writer
+	config *FilterConfig
+
+	// 複数入力をマージする場合の行頭ラベル
+	prefixed bool
+	name     string // ユーザー指定のラベル
+	fallback string // session_id が得られない場合のラベル
+	label    string
+	color    string
+
+	github   *githubActions // GitHub Actions のワークフローコマンドを出力する場合の状態
+	recorder *recorder      // --record で入力行を記録する場合の書き出し先
+}
+
+// newStream は stream を作成 (追跡状態を持たせるため config は複製する)
+func newStream(output io.Writer, config *FilterConfig) *stream {
+	cfg := *config
+	cfg.Tracker = newTracker()
+	s := &stream{
+		output: output,
+		config: &cfg,
+	}
+	if cfg.GitHubActions && cfg.Format == "text" {
+		s.github = newGitHubActions(&githubGroup{})
+	}
+	return s
+}
+
+// processLine は到着時刻 at に届いた1行分のJSONを処理して出力
+func (s *stream) processLine(line string, at time.Time) {
+	// 空行はスキップ
+	if line == "" {
+		return
+	}
+
+	// メッセージタイプを判定
+	msg, err := parseMessage(line)
+	if err != nil {
+		// JSONパースエラーは警告を出してスキップ
+		fmt.Fprintf(os.Stderr, "Warning: failed to parse JSON: %v\n", err)
+		return
+	}
+
+	// 以降の表示・追跡・レポートのすべてで秘密情報を伏せる
+	if s.config.Redactor != nil {
+		line = s.config.Redactor.redactLine(line)
+	}
+	if s.config.Anonymizer != nil {
+		line = s.config.Anonymizer.anonymizeLine(line)
+		// ラベルにも仮名の session_id を使う
+		if m, err := parseMessage(line); err == nil {
+			msg = m
+		}
+	}
+	// 記録にも伏せた後の行を書き出す
+	if s.recorder != nil {
+		if err := s.recorder.record(at, line); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record line: %v\n", err)
+		}
+	}
+
+	if s.prefixed && s.label == "" {
+		s.label = sessionLabel(s.name, msg.SessionID, s.fallback)
+	}
+
+	s.config.Tracker.observe(msg.Type, []byte(line), at)
+
+	// stream-json は ccfilter で再び読めるよう、フィルタせずに1行ずつそのまま出力する
+	if s.config.Format == "stream-json" {
+		fmt.Fprintln(s.output, line)
+		return
+	}
+
+	if s.github != nil {
+		s.githubBefore(msg.Type, []byte(line))
+		defer s.githubAfter(msg.Type, []byte(line))
+	}
+
+	// フィルタリング
+	if !shouldDisplay(msg.Type, s.config) {
+		return
+	}
+
+	// json は表示対象のメッセージを1行1メッセージの JSON で出力する (複数入力でもラベルは付けない)
+	if s.config.Format == "json" {
+		fmt.Fprintln(s.output, line)
+		return
+	}
+
+	// フォーマット
+	formatted, err := formatMessage(msg.Type, []byte(line), s.config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to format message: %v\n", err)
+		return
+	}
+
+	// 出力
+	if formatted != "" {
+		s.write(formatted)
+	}
+}
+
+// finish は入力の終了時に呼び、終了時のレポートを出力
+func (s *stream) finish() {
+	s.config.Tracker.close()
+	if s.github != nil {
+		s.githubEndGroup()
+	}
+
+	// 統計情報とタイムラインはテキストなので、JSON や Markdown などの出力には混ぜない
+	if !isTextFormat(s.config.Format) {
+		return
+	}
+	if s.config.ShowStats {
+		s.write(formatStats(computeStats(s.config.Tracker), s.config))
+	}
+	if s.config.ShowTimeline {
+		if timeline := formatTimeline(s.config.Tracker, s.config); timeline != "" {
+			s.write(timeline)
+		}
+	}
+}
+
+// write はフォーマット済みテキストを出力 (必要に応じてラベルを付与)
+func (s *stream) write(text string) {
+	if s.prefixed {
+		text = prefixLines(text, s.prefix())
+	}
+	if s.github != nil {
+		text = stopCommands(text)
+	}
+	fmt.Fprint(s.output, text)
+}
+
+// prefix は行頭に付与するラベル文字列を返す
+func (s *stream) prefix() string {
+	label := s.label
+	if label == "" {
+		label = sessionLabel(s.name, "", s.fallback)
+	}
+	return colorize("["+label+"]", s.color, s.config.colors()) + " "
+}
+
+// sessionLabel はラベルを決定 (ユーザー指定 > session_id の先頭8文字 > フォールバック)
+func sessionLabel(name, sessionID, fallback string) string {
+	if name != "" {
+		return name
+	}
+	if sessionID != "" {
+		if len(sessionID) > 8 {
+			return sessionID[:8]
+		}
+		return sessionID
+	}
+	return fallback
+}
+
+// prefixLines は各行の先頭に prefix を付与
+func prefixLines(text, prefix string) string {
+	trailing := strings.HasSuffix(text, "\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	var output strings.Builder
+	for i, line := range lines {
+		output.WriteString(prefix)
+		output.WriteString(line)
+		if i < len(lines)-1 || trailing {
+			output.WriteString("\n")
+		}
+	}
+	return output.String()
+}
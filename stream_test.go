@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestSessionLabel(t *testing.T) {
+	tests := []struct {
+		name      string
+		userName  string
+		sessionID string
+		fallback  string
+		want      string
+	}{
+		{name: "user name wins", userName: "api", sessionID: "ef076ce9-9d77", fallback: "run1", want: "api"},
+		{name: "session id prefix", sessionID: "ef076ce9-9d77", fallback: "run1", want: "ef076ce9"},
+		{name: "short session id", sessionID: "abc", fallback: "run1", want: "abc"},
+		{name: "fallback", fallback: "run1", want: "run1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sessionLabel(tt.userName, tt.sessionID, tt.fallback); got != tt.want {
+				t.Errorf("sessionLabel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixLines(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "single line", text: "hello\n", want: "[a] hello\n"},
+		{name: "multiple lines", text: "one\ntwo\n", want: "[a] one\n[a] two\n"},
+		{name: "leading empty line", text: "\nresult\n", want: "[a] \n[a] result\n"},
+		{name: "no trailing newline", text: "one\ntwo", want: "[a] one\n[a] two"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := prefixLines(tt.text, "[a] "); got != tt.want {
+				t.Errorf("prefixLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
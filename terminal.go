@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// validColorModes は --color で指定できる値
+var validColorModes = []string{"auto", "always", "never"}
+
+// writerCapabilities は出力先が端末かどうかと端末の幅を判定する (テストで差し替えられるようにする)
+type writerCapabilities interface {
+	IsTerminal() bool
+	Width() int // 端末の幅 (端末でない場合や取得できない場合は0)
+}
+
+// fileCapabilities は *os.File の出力先
+type fileCapabilities struct {
+	f *os.File
+}
+
+// IsTerminal はキャラクタデバイス (端末) かどうかを判定
+func (c fileCapabilities) IsTerminal() bool {
+	info, err := c.f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Width は端末の幅を返す
+func (c fileCapabilities) Width() int {
+	if !c.IsTerminal() {
+		return 0
+	}
+	return terminalWidth(c.f)
+}
+
+// pipeCapabilities はファイル以外の出力先 (常に端末ではない)
+type pipeCapabilities struct{}
+
+func (pipeCapabilities) IsTerminal() bool {
+	return false
+}
+
+func (pipeCapabilities) Width() int {
+	return 0
+}
+
+// capabilitiesOf は出力先の writerCapabilities を返す
+func capabilitiesOf(w io.Writer) writerCapabilities {
+	if f, ok := w.(*os.File); ok {
+		return fileCapabilities{f: f}
+	}
+	return pipeCapabilities{}
+}
+
+// detectColor は --color=auto のときにカラー出力するかどうかを判定
+// NO_COLOR > FORCE_COLOR > TERM=dumb > 出力先が端末かどうか の順に優先する
+func detectColor(w writerCapabilities, getenv func(string) string) bool {
+	if getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force := getenv("FORCE_COLOR"); force != "" {
+		return force != "0" && force != "false"
+	}
+	if getenv("TERM") == "dumb" {
+		return false
+	}
+	return w.IsTerminal()
+}
+
+// detectWidth は折り返しに使う幅を判定 (COLUMNS > 端末の幅)
+// 出力先が端末でない場合は COLUMNS があっても0で折り返さない (幅を指定する場合は --width)
+func detectWidth(w writerCapabilities, getenv func(string) string) int {
+	if !w.IsTerminal() {
+		return 0
+	}
+	if n, err := strconv.Atoi(getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return w.Width()
+}
+
+// colorFlag は --color[=auto|always|never] (値を省略した場合は always)
+type colorFlag struct {
+	mode *string
+}
+
+func (f colorFlag) String() string {
+	if f.mode == nil {
+		return ""
+	}
+	return *f.mode
+}
+
+func (f colorFlag) Set(value string) error {
+	switch value {
+	case "true":
+		value = "always"
+	case "false":
+		value = "never"
+	}
+	for _, mode := range validColorModes {
+		if value == mode {
+			*f.mode = value
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of auto, always, never")
+}
+
+// IsBoolFlag は値を省略した --color を許可する
+func (f colorFlag) IsBoolFlag() bool {
+	return true
+}
+
+// checkColorArgs は --color always のように空白で区切った値を拒否する
+// 値を省略できるフラグなので、そのままでは always が入力ファイルの名前として扱われてしまう
+func checkColorArgs(args []string) error {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "--" {
+			return nil
+		}
+		if args[i] != "--color" && args[i] != "-color" {
+			continue
+		}
+		if slices.Contains(validColorModes, args[i+1]) {
+			return fmt.Errorf("use --color=%s instead of --color %s", args[i+1], args[i+1])
+		}
+	}
+	return nil
+}
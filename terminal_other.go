@@ -0,0 +1,10 @@
+//go:build !(linux || darwin)
+
+package main
+
+import "os"
+
+// terminalWidth は端末の幅を取得できない環境では0を返す (COLUMNS か --width で指定する)
+func terminalWidth(f *os.File) int {
+	return 0
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// fakeTerminal はテスト用の writerCapabilities
+type fakeTerminal struct {
+	terminal bool
+	width    int
+}
+
+func (f fakeTerminal) IsTerminal() bool {
+	return f.terminal
+}
+
+func (f fakeTerminal) Width() int {
+	return f.width
+}
+
+func TestDetectColor(t *testing.T) {
+	tests := []struct {
+		name     string
+		terminal bool
+		env      map[string]string
+		want     bool
+	}{
+		{name: "terminal", terminal: true, want: true},
+		{name: "pipe", terminal: false, want: false},
+		{name: "NO_COLOR on terminal", terminal: true, env: map[string]string{"NO_COLOR": "1"}, want: false},
+		{name: "NO_COLOR wins over FORCE_COLOR", terminal: true, env: map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "1"}, want: false},
+		{name: "FORCE_COLOR on pipe", terminal: false, env: map[string]string{"FORCE_COLOR": "1"}, want: true},
+		{name: "FORCE_COLOR=0", terminal: true, env: map[string]string{"FORCE_COLOR": "0"}, want: false},
+		{name: "FORCE_COLOR wins over TERM=dumb", terminal: false, env: map[string]string{"FORCE_COLOR": "true", "TERM": "dumb"}, want: true},
+		{name: "TERM=dumb", terminal: true, env: map[string]string{"TERM": "dumb"}, want: false},
+		{name: "TERM=xterm", terminal: true, env: map[string]string{"TERM": "xterm-256color"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			if got := detectColor(fakeTerminal{terminal: tt.terminal}, getenv); got != tt.want {
+				t.Errorf("detectColor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+	if capabilitiesOf(&bytes.Buffer{}).IsTerminal() {
+		t.Error("bytes.Buffer should not be a terminal")
+	}
+
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if capabilitiesOf(f).IsTerminal() {
+		t.Error("regular file should not be a terminal")
+	}
+	if w := capabilitiesOf(f).Width(); w != 0 {
+		t.Errorf("regular file width = %d, want 0", w)
+	}
+}
+
+func TestDetectWidth(t *testing.T) {
+	tests := []struct {
+		name     string
+		terminal fakeTerminal
+		columns  string
+		want     int
+	}{
+		{name: "terminal", terminal: fakeTerminal{terminal: true, width: 120}, want: 120},
+		{name: "pipe", terminal: fakeTerminal{}, want: 0},
+		{name: "COLUMNS", terminal: fakeTerminal{terminal: true, width: 120}, columns: "60", want: 60},
+		{name: "COLUMNS on pipe", terminal: fakeTerminal{}, columns: "60", want: 0},
+		{name: "invalid COLUMNS", terminal: fakeTerminal{terminal: true, width: 120}, columns: "wide", want: 120},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string {
+				if key == "COLUMNS" {
+					return tt.columns
+				}
+				return ""
+			}
+			if got := detectWidth(tt.terminal, getenv); got != tt.want {
+				t.Errorf("detectWidth() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// テーマで色を割り当てる役割
+const (
+	roleArrow    = "arrow"    // ツール呼び出しと結果の矢印
+	roleTool     = "tool"     // ツール名
+	roleSubagent = "subagent" // サブエージェントを起動する Task ツール
+	roleError    = "error"    // エラー
+	roleMetrics  = "metrics"  // 結果のメトリクスやレイテンシ
+	roleMuted    = "muted"    // 区切り線や枠などの補助的な表示
+	roleDiffAdd  = "diff-add" // 差分の追加行
+	roleDiffDel  = "diff-del" // 差分の削除行
+	roleThinking = "thinking" // thinking ブロック
+	roleHeading  = "heading"  // Markdown の見出し
+	roleCode     = "code"     // Markdown のインラインコード
+	roleLink     = "link"     // Markdown のリンク先
+	roleBullet   = "bullet"   // Markdown のリストの記号
+	roleSuccess  = "success"  // 完了したタスク
+	roleKeyword  = "keyword"  // ハイライトのキーワード
+	roleString   = "string"   // ハイライトの文字列
+	roleComment  = "comment"  // ハイライトのコメント
+	roleNumber   = "number"   // ハイライトの数値
+	roleKey      = "key"      // ハイライトの JSON/YAML のキー
+	roleVariable = "variable" // ハイライトのシェルの変数
+)
+
+// themeRoles はテーマで指定できる役割
+var themeRoles = []string{
+	roleArrow, roleTool, roleSubagent, roleError, roleMetrics, roleMuted, roleDiffAdd, roleDiffDel, roleThinking,
+	roleHeading, roleCode, roleLink, roleBullet, roleSuccess,
+	roleKeyword, roleString, roleComment, roleNumber, roleKey, roleVariable,
+}
+
+// defaultTheme はテーマを指定しない場合に使うテーマ
+const defaultTheme = "dark"
+
+// colorDepth は端末が表示できる色数
+type colorDepth int
+
+const (
+	depth16        colorDepth = iota // 16色
+	depth256                         // 256色
+	depthTrueColor                   // 24bit カラー
+)
+
+// Color は前景色・背景色の指定
+type Color struct {
+	depth   colorDepth // 指定された形式 (16色の番号、256色の番号、RGB)
+	index   int        // 16色または256色の番号
+	r, g, b int
+	set     bool
+}
+
+// Style は役割ごとの表示スタイル
+type Style struct {
+	FG        Color
+	BG        Color
+	Bold      bool
+	Dim       bool
+	Italic    bool
+	Underline bool
+}
+
+// Theme は役割からスタイルへの対応
+type Theme struct {
+	Name   string
+	Styles map[string]Style
+}
+
+// basicColorNames は16色の名前 (番号順)
+var basicColorNames = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"gray", "bright-red", "bright-green", "bright-yellow", "bright-blue", "bright-magenta", "bright-cyan", "bright-white",
+}
+
+// basicColorRGB は16色の代表的な RGB 値 (xterm)
+var basicColorRGB = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0}, {0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0}, {92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// cubeLevels は256色の 6x6x6 のカラーキューブの各成分の値
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// builtinThemes は組み込みのテーマ
+var builtinThemes = map[string]*Theme{
+	"dark": mustTheme("dark", map[string]string{
+		roleArrow: "cyan", roleTool: "blue", roleSubagent: "magenta", roleError: "red",
+		roleMetrics: "gray", roleMuted: "gray", roleDiffAdd: "green", roleDiffDel: "red",
+		roleThinking: "dim italic", roleHeading: "bold cyan", roleCode: "yellow", roleLink: "gray",
+		roleBullet: "cyan", roleSuccess: "green",
+		roleKeyword: "blue", roleString: "green", roleComment: "gray", roleNumber: "yellow",
+		roleKey: "cyan", roleVariable: "cyan",
+	}),
+	"light": mustTheme("light", map[string]string{
+		roleArrow: "30", roleTool: "bold 25", roleSubagent: "bold 90", roleError: "bold 160",
+		roleMetrics: "242", roleMuted: "245", roleDiffAdd: "28 on 194", roleDiffDel: "124 on 224",
+		roleThinking: "italic 243", roleHeading: "bold 25", roleCode: "130", roleLink: "242",
+		roleBullet: "30", roleSuccess: "28",
+		roleKeyword: "25", roleString: "28", roleComment: "italic 244", roleNumber: "130",
+		roleKey: "30", roleVariable: "90",
+	}),
+	"solarized": mustTheme("solarized", map[string]string{
+		roleArrow: "#2aa198", roleTool: "#268bd2", roleSubagent: "#6c71c4", roleError: "bold #dc322f",
+		roleMetrics: "#586e75", roleMuted: "#586e75", roleDiffAdd: "#859900", roleDiffDel: "#dc322f",
+		roleThinking: "italic #657b83", roleHeading: "bold #b58900", roleCode: "#cb4b16", roleLink: "#586e75",
+		roleBullet: "#2aa198", roleSuccess: "#859900",
+		roleKeyword: "#859900", roleString: "#2aa198", roleComment: "italic #586e75", roleNumber: "#d33682",
+		roleKey: "#268bd2", roleVariable: "#b58900",
+	}),
+	"mono": mustTheme("mono", map[string]string{
+		roleTool: "bold", roleSubagent: "bold underline", roleError: "bold underline",
+		roleMetrics: "dim", roleMuted: "dim", roleDiffAdd: "bold", roleDiffDel: "dim",
+		roleThinking: "dim italic", roleHeading: "bold", roleCode: "bold", roleLink: "dim",
+		roleSuccess: "bold", roleKeyword: "bold", roleComment: "dim",
+	}),
+}
+
+// palette はカラー出力に使うテーマと色数 (nil の場合はカラー出力しない)
+type palette struct {
+	theme *Theme
+	depth colorDepth
+}
+
+// colors は config のカラー出力に使うテーマと色数を返す (カラー出力が無効な場合は nil)
+func (c *FilterConfig) colors() *palette {
+	if !c.UseColor {
+		return nil
+	}
+	theme := c.Theme
+	if theme == nil {
+		theme = builtinThemes[defaultTheme]
+	}
+	return &palette{theme: theme, depth: c.ColorDepth}
+}
+
+// themeNames は組み込みのテーマの名前を返す
+func themeNames() []string {
+	var names []string
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mustTheme は組み込みのテーマを作成
+func mustTheme(name string, specs map[string]string) *Theme {
+	theme, err := newTheme(name, nil, specs)
+	if err != nil {
+		panic(err)
+	}
+	return theme
+}
+
+// newTheme は base を元に specs の役割を上書きしたテーマを作成
+func newTheme(name string, base *Theme, specs map[string]string) (*Theme, error) {
+	theme := &Theme{Name: name, Styles: make(map[string]Style)}
+	if base != nil {
+		for role, style := range base.Styles {
+			theme.Styles[role] = style
+		}
+	}
+	for role, spec := range specs {
+		if !isThemeRole(role) {
+			return nil, fmt.Errorf("theme %q: unknown role %q (valid roles: %s)", name, role, strings.Join(themeRoles, ", "))
+		}
+		style, err := parseStyle(spec)
+		if err != nil {
+			return nil, fmt.Errorf("theme %q: %s: %w", name, role, err)
+		}
+		theme.Styles[role] = style
+	}
+	return theme, nil
+}
+
+// isThemeRole はテーマで指定できる役割かどうかを判定
+func isThemeRole(role string) bool {
+	for _, r := range themeRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStyle は "bold italic #ff8800 on 236" のようなスタイルの指定を解析
+// 色は16色の名前、256色の番号、#rrggbb で指定し、"on" の後は背景色
+func parseStyle(spec string) (Style, error) {
+	var style Style
+	background := false
+	for _, word := range strings.Fields(spec) {
+		switch word {
+		case "bold":
+			style.Bold = true
+			continue
+		case "dim":
+			style.Dim = true
+			continue
+		case "italic":
+			style.Italic = true
+			continue
+		case "underline":
+			style.Underline = true
+			continue
+		case "on":
+			background = true
+			continue
+		}
+		color, err := parseColor(word)
+		if err != nil {
+			return Style{}, err
+		}
+		if background {
+			style.BG = color
+		} else {
+			style.FG = color
+		}
+	}
+	return style, nil
+}
+
+// parseColor は色の名前、256色の番号、#rrggbb を解析
+func parseColor(s string) (Color, error) {
+	for i, name := range basicColorNames {
+		if s == name || (name == "gray" && (s == "grey" || s == "bright-black")) {
+			return Color{depth: depth16, index: i, set: true}, nil
+		}
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 255 {
+		return Color{depth: depth256, index: n, set: true}, nil
+	}
+	if len(s) == 7 && s[0] == '#' {
+		if v, err := strconv.ParseUint(s[1:], 16, 32); err == nil {
+			return Color{depth: depthTrueColor, r: int(v >> 16 & 0xff), g: int(v >> 8 & 0xff), b: int(v & 0xff), set: true}, nil
+		}
+	}
+	return Color{}, fmt.Errorf("invalid color %q (use a color name, 0-255 or #rrggbb)", s)
+}
+
+// lookup は役割のスタイルを返す (役割でない場合は16色の名前として扱う)
+func (t *Theme) lookup(role string) (Style, bool) {
+	if style, ok := t.Styles[role]; ok {
+		return style, true
+	}
+	for i, name := range basicColorNames {
+		if role == name {
+			return Style{FG: Color{depth: depth16, index: i, set: true}}, true
+		}
+	}
+	return Style{}, false
+}
+
+// open は役割のスタイルを開始するエスケープシーケンスを返す
+func (p *palette) open(role string) string {
+	if p == nil {
+		return ""
+	}
+	style, _ := p.theme.lookup(role)
+	return style.open(p.depth)
+}
+
+// close は役割のスタイルを解除するエスケープシーケンスを返す
+// (リセットせずに個別に解除するので、見出しなど外側のスタイルを保てる)
+func (p *palette) close(role string) string {
+	if p == nil {
+		return ""
+	}
+	style, _ := p.theme.lookup(role)
+	return style.close()
+}
+
+// open はスタイルを開始するエスケープシーケンスを返す
+func (s Style) open(depth colorDepth) string {
+	var output strings.Builder
+	for _, attr := range []struct {
+		on   bool
+		code string
+	}{{s.Bold, StyleBold}, {s.Dim, StyleDim}, {s.Italic, StyleItalic}, {s.Underline, StyleUnderline}} {
+		if attr.on {
+			output.WriteString(attr.code)
+		}
+	}
+	if s.FG.set {
+		output.WriteString("\x1b[" + s.FG.sgr(depth, false) + "m")
+	}
+	if s.BG.set {
+		output.WriteString("\x1b[" + s.BG.sgr(depth, true) + "m")
+	}
+	return output.String()
+}
+
+// close はスタイルを解除するエスケープシーケンスを返す
+func (s Style) close() string {
+	var output strings.Builder
+	if s.FG.set {
+		output.WriteString(StyleForegroundOff)
+	}
+	if s.BG.set {
+		output.WriteString(StyleBackgroundOff)
+	}
+	if s.Underline {
+		output.WriteString(StyleUnderlineOff)
+	}
+	if s.Italic {
+		output.WriteString(StyleItalicOff)
+	}
+	if s.Bold || s.Dim {
+		output.WriteString(StyleBoldOff)
+	}
+	return output.String()
+}
+
+// sgr は色を depth で表示できる SGR パラメータに変換 (表示できない場合は近い色に落とす)
+func (c Color) sgr(depth colorDepth, background bool) string {
+	base := 30
+	if background {
+		base = 40
+	}
+	switch {
+	case c.depth == depthTrueColor && depth == depthTrueColor:
+		return fmt.Sprintf("%d;2;%d;%d;%d", base+8, c.r, c.g, c.b)
+	case c.depth != depth16 && depth >= depth256:
+		return fmt.Sprintf("%d;5;%d", base+8, c.to256())
+	}
+	n := c.to16()
+	if n >= 8 {
+		return strconv.Itoa(base + 60 + n - 8)
+	}
+	return strconv.Itoa(base + n)
+}
+
+// rgb は色の RGB 値を返す
+func (c Color) rgb() (int, int, int) {
+	switch {
+	case c.depth == depthTrueColor:
+		return c.r, c.g, c.b
+	case c.index < 16:
+		v := basicColorRGB[c.index]
+		return v[0], v[1], v[2]
+	case c.index < 232:
+		n := c.index - 16
+		return cubeLevels[n/36], cubeLevels[n/6%6], cubeLevels[n%6]
+	default:
+		v := 8 + (c.index-232)*10
+		return v, v, v
+	}
+}
+
+// to256 は色を256色の番号に変換
+func (c Color) to256() int {
+	if c.depth != depthTrueColor {
+		return c.index
+	}
+	// カラーキューブとグレースケールのうち近い方
+	cube := 16 + 36*nearestLevel(c.r) + 6*nearestLevel(c.g) + nearestLevel(c.b)
+	gray := 232 + max(min(((c.r+c.g+c.b)/3-3)/10, 23), 0)
+	if colorDistance(c, Color{depth: depth256, index: gray}) < colorDistance(c, Color{depth: depth256, index: cube}) {
+		return gray
+	}
+	return cube
+}
+
+// to16 は色を16色の番号に変換
+func (c Color) to16() int {
+	if c.depth == depth16 {
+		return c.index
+	}
+	best, bestDistance := 0, -1
+	for i := range basicColorRGB {
+		d := colorDistance(c, Color{depth: depth16, index: i})
+		if bestDistance < 0 || d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return best
+}
+
+// nearestLevel はカラーキューブの成分のうち v に最も近いものの番号を返す
+func nearestLevel(v int) int {
+	best := 0
+	for i, level := range cubeLevels {
+		if abs(level-v) < abs(cubeLevels[best]-v) {
+			best = i
+		}
+	}
+	return best
+}
+
+// colorDistance は2色の RGB 空間での距離の2乗を返す
+func colorDistance(a, b Color) int {
+	ar, ag, ab := a.rgb()
+	br, bg, bb := b.rgb()
+	return (ar-br)*(ar-br) + (ag-bg)*(ag-bg) + (ab-bb)*(ab-bb)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// detectColorDepth は環境変数から端末の色数を判定
+// FORCE_COLOR=2/3 (256色/24bit)、COLORTERM=truecolor、TERM=*-256color の順に見る
+func detectColorDepth(getenv func(string) string) colorDepth {
+	switch getenv("FORCE_COLOR") {
+	case "3":
+		return depthTrueColor
+	case "2":
+		return depth256
+	}
+	switch getenv("COLORTERM") {
+	case "truecolor", "24bit":
+		return depthTrueColor
+	}
+	if strings.Contains(getenv("TERM"), "256color") {
+		return depth256
+	}
+	return depth16
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStyle(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		depth   colorDepth
+		want    string
+		wantErr bool
+	}{
+		{name: "basic", spec: "cyan", depth: depth16, want: "\x1b[36m"},
+		{name: "bright", spec: "bright-blue", depth: depth16, want: "\x1b[94m"},
+		{name: "attributes", spec: "bold italic red", depth: depth16, want: StyleBold + StyleItalic + "\x1b[31m"},
+		{name: "256", spec: "208", depth: depth256, want: "\x1b[38;5;208m"},
+		{name: "truecolor", spec: "#ff8800", depth: depthTrueColor, want: "\x1b[38;2;255;136;0m"},
+		{name: "background", spec: "white on 160", depth: depth256, want: "\x1b[37m\x1b[48;5;160m"},
+		{name: "truecolor to 256", spec: "#ff8700", depth: depth256, want: "\x1b[38;5;208m"},
+		{name: "truecolor gray to 256", spec: "#808080", depth: depth256, want: "\x1b[38;5;244m"},
+		{name: "truecolor to 16", spec: "#ff1010", depth: depth16, want: "\x1b[91m"},
+		{name: "256 to 16", spec: "28", depth: depth16, want: "\x1b[32m"},
+		{name: "256 background to 16", spec: "on 21", depth: depth16, want: "\x1b[44m"},
+		{name: "16 is kept", spec: "gray", depth: depthTrueColor, want: "\x1b[90m"},
+		{name: "256 on truecolor", spec: "28 on 194", depth: depthTrueColor, want: "\x1b[38;5;28m\x1b[48;5;194m"},
+		{name: "unknown color", spec: "bold purple", wantErr: true},
+		{name: "out of range", spec: "256", wantErr: true},
+		{name: "bad hex", spec: "#12345", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style, err := parseStyle(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStyle(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := style.open(tt.depth); got != tt.want {
+				t.Errorf("open() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStyleClose(t *testing.T) {
+	style, err := parseStyle("bold underline cyan on 236")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := StyleForegroundOff + StyleBackgroundOff + StyleUnderlineOff + StyleBoldOff
+	if got := style.close(); got != want {
+		t.Errorf("close() = %q, want %q", got, want)
+	}
+}
+
+func TestBuiltinThemes(t *testing.T) {
+	for _, name := range themeNames() {
+		theme := builtinThemes[name]
+		for _, role := range []string{roleTool, roleError, roleHeading, roleKeyword} {
+			if _, ok := theme.Styles[role]; !ok {
+				t.Errorf("theme %s has no style for %s", name, role)
+			}
+		}
+	}
+
+	// dark は従来の16色の配色
+	dark := builtinThemes["dark"]
+	for role, want := range map[string]string{roleArrow: ColorCyan, roleTool: ColorBlue, roleError: ColorRed, roleMetrics: ColorGray} {
+		if got := dark.Styles[role].open(depth16); got != want {
+			t.Errorf("dark %s = %q, want %q", role, got, want)
+		}
+	}
+}
+
+func TestColorize_Theme(t *testing.T) {
+	light := (&FilterConfig{UseColor: true, Theme: builtinThemes["light"], ColorDepth: depth256}).colors()
+	if got, want := colorize("Read", roleTool, light), StyleBold+"\x1b[38;5;25mRead"+ColorReset; got != want {
+		t.Errorf("colorize() = %q, want %q", got, want)
+	}
+	// 16色の名前はテーマによらず使える
+	if got, want := colorize("x", "green", light), ColorGreen+"x"+ColorReset; got != want {
+		t.Errorf("colorize() = %q, want %q", got, want)
+	}
+
+	mono := (&FilterConfig{UseColor: true, Theme: builtinThemes["mono"], ColorDepth: depthTrueColor}).colors()
+	if got := colorize("→", roleArrow, mono); got != "→" {
+		t.Errorf("mono arrow should be plain, got %q", got)
+	}
+	if got := renderMarkdown("## Title", mono); got != StyleBold+"Title"+StyleBoldOff {
+		t.Errorf("renderMarkdown() = %q", got)
+	}
+}
+
+func TestNewTheme(t *testing.T) {
+	theme, err := newTheme("mine", builtinThemes["dark"], map[string]string{roleTool: "bold #005f87", "thinking": "italic 245"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := theme.Styles[roleTool].open(depth256), StyleBold+"\x1b[38;5;24m"; got != want {
+		t.Errorf("tool = %q, want %q", got, want)
+	}
+	if got, want := theme.Styles[roleThinking].open(depth256), StyleItalic+"\x1b[38;5;245m"; got != want {
+		t.Errorf("thinking = %q, want %q", got, want)
+	}
+	if theme.Styles[roleError] != builtinThemes["dark"].Styles[roleError] {
+		t.Error("roles not overridden should come from the base theme")
+	}
+
+	if _, err := newTheme("mine", nil, map[string]string{"tools": "red"}); err == nil || !strings.Contains(err.Error(), `unknown role "tools"`) {
+		t.Errorf("expected unknown role error, got %v", err)
+	}
+	if _, err := newTheme("mine", nil, map[string]string{roleTool: "reddish"}); err == nil {
+		t.Error("expected invalid color error")
+	}
+}
+
+func TestDetectColorDepth(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want colorDepth
+	}{
+		{name: "unknown", env: map[string]string{"TERM": "xterm"}, want: depth16},
+		{name: "256color", env: map[string]string{"TERM": "xterm-256color"}, want: depth256},
+		{name: "truecolor", env: map[string]string{"TERM": "xterm-256color", "COLORTERM": "truecolor"}, want: depthTrueColor},
+		{name: "24bit", env: map[string]string{"COLORTERM": "24bit"}, want: depthTrueColor},
+		{name: "FORCE_COLOR=2", env: map[string]string{"FORCE_COLOR": "2", "COLORTERM": "truecolor"}, want: depth256},
+		{name: "FORCE_COLOR=3", env: map[string]string{"FORCE_COLOR": "3"}, want: depthTrueColor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectColorDepth(func(key string) string { return tt.env[key] }); got != tt.want {
+				t.Errorf("detectColorDepth() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// traceEvent は Chrome Trace Event Format の1イベント
+type traceEvent struct {
+	Name     string                 `json:"name"`
+	Category string                 `json:"cat,omitempty"`
+	Phase    string                 `json:"ph"`
+	Ts       int64                  `json:"ts"` // マイクロ秒
+	Dur      int64                  `json:"dur"`
+	Pid      int                    `json:"pid"`
+	Tid      int                    `json:"tid"`
+	Args     map[string]interface{} `json:"args,omitempty"`
+}
+
+// traceFile は Perfetto や chrome://tracing で開ける JSON
+type traceFile struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// writeTraceEvents は全セッションを Chrome Trace Event 形式で書き出す
+func writeTraceEvents(path string, streams []*stream) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create trace events file: %w", err)
+	}
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(newTraceFile(streams))
+}
+
+// newTraceFile はセッションごとにプロセス、メインエージェントとサブエージェントごとにスレッド (トラック) を割り当てる
+// 時刻は最初に始まったセッションからの経過時間
+func newTraceFile(streams []*stream) traceFile {
+	var origin time.Time
+	for _, s := range streams {
+		if start := s.config.Tracker.Start; !start.IsZero() && (origin.IsZero() || start.Before(origin)) {
+			origin = start
+		}
+	}
+
+	file := traceFile{TraceEvents: []traceEvent{}, DisplayTimeUnit: "ms"}
+	for i, s := range streams {
+		file.TraceEvents = append(file.TraceEvents, sessionTraceEvents(s, i+1, origin)...)
+	}
+	return file
+}
+
+// sessionTraceEvents は1セッション分のイベントを作成
+func sessionTraceEvents(s *stream, pid int, origin time.Time) []traceEvent {
+	t := s.config.Tracker
+	name := sessionLabel(s.name, t.SessionID(), fmt.Sprintf("session %d", pid))
+	events := []traceEvent{
+		metadataEvent("process_name", pid, 0, "claude "+name),
+		metadataEvent("thread_name", pid, 1, "main agent"),
+	}
+
+	// parent_tool_use_id ごとに出現順でトラックを割り当てる
+	tids := map[string]int{"": 1}
+	for _, step := range t.Steps {
+		tid, ok := tids[step.ParentID]
+		if !ok {
+			tid = len(tids) + 1
+			tids[step.ParentID] = tid
+			events = append(events, metadataEvent("thread_name", pid, tid, subagentTrackName(t, step.ParentID)))
+		}
+
+		event := traceEvent{
+			Phase: "X",
+			Ts:    step.Start.Sub(origin).Microseconds(),
+			Dur:   step.Duration().Microseconds(),
+			Pid:   pid,
+			Tid:   tid,
+		}
+		switch step.Kind {
+		case "turn":
+			event.Name = "turn " + step.Name
+			event.Category = "turn"
+			event.Args = map[string]interface{}{
+				"model":         step.Name,
+				"input_tokens":  step.Usage.InputTokens,
+				"output_tokens": step.Usage.OutputTokens,
+			}
+		case "tool":
+			event.Name = step.Name
+			event.Category = "tool"
+			event.Args = map[string]interface{}{"is_error": step.IsError}
+			if _, param, ok := mainParam(step.Name, step.Input); ok {
+				event.Args["parameter"] = param
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// subagentTrackName はサブエージェントのトラック名 (Task の description を使う)
+func subagentTrackName(t *Tracker, parentToolUseID string) string {
+	if call := t.Call(parentToolUseID); call != nil {
+		var input struct {
+			Description  string `json:"description"`
+			SubagentType string `json:"subagent_type"`
+		}
+		if err := json.Unmarshal(call.Input, &input); err == nil {
+			if desc := joinNonEmpty(": ", input.SubagentType, input.Description); desc != "" {
+				return "subagent " + desc
+			}
+		}
+	}
+	return "subagent " + parentToolUseID
+}
+
+// metadataEvent はプロセスやスレッドの名前を付けるメタデータイベント
+func metadataEvent(name string, pid, tid int, value string) traceEvent {
+	return traceEvent{
+		Name:  name,
+		Phase: "M",
+		Pid:   pid,
+		Tid:   tid,
+		Args:  map[string]interface{}{"name": value},
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewTraceFile(t *testing.T) {
+	tracker := trackLines(t, []int{0, 1, 2, 4, 6, 7}, subagentSessionLines)
+	streams := []*stream{{config: &FilterConfig{Tracker: tracker}}}
+
+	file := newTraceFile(streams)
+
+	type want struct {
+		name  string
+		phase string
+		tid   int
+		ts    int64
+		dur   int64
+	}
+	wants := []want{
+		{"process_name", "M", 0, 0, 0},
+		{"thread_name", "M", 1, 0, 0},
+		{"turn sonnet", "X", 1, 0, 1_000_000},
+		{"Task", "X", 1, 1_000_000, 5_000_000},
+		{"thread_name", "M", 2, 0, 0},
+		{"turn haiku", "X", 2, 1_000_000, 1_000_000},
+		{"Read", "X", 2, 2_000_000, 2_000_000},
+	}
+	if len(file.TraceEvents) != len(wants) {
+		t.Fatalf("got %d events, want %d: %+v", len(file.TraceEvents), len(wants), file.TraceEvents)
+	}
+	for i, w := range wants {
+		e := file.TraceEvents[i]
+		if e.Name != w.name || e.Phase != w.phase || e.Pid != 1 || e.Tid != w.tid || e.Ts != w.ts || e.Dur != w.dur {
+			t.Errorf("event[%d] = %+v, want %+v", i, e, w)
+		}
+	}
+
+	if got := file.TraceEvents[0].Args["name"]; got != "claude s1" {
+		t.Errorf("process name = %v", got)
+	}
+	if got := file.TraceEvents[4].Args["name"]; got != "subagent explore" {
+		t.Errorf("subagent track name = %v", got)
+	}
+	if got := file.TraceEvents[6].Args["is_error"]; got != true {
+		t.Errorf("Read is_error = %v", got)
+	}
+}
+
+func TestWriteTraceEvents_MultipleSessions(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.jsonl"), filepath.Join(dir, "b.jsonl")
+	writeFile(t, a, strings.Join(subagentSessionLines, "\n"))
+	writeFile(t, b, `{"type":"assistant","message":{"id":"m1","model":"opus","content":[{"type":"text","text":"hi"}]}}`)
+
+	path := filepath.Join(dir, "trace.json")
+	config := NewFilterConfig()
+	config.TraceEventsPath = path
+	sources := []InputSource{{Path: a}, {Name: "web", Path: b}}
+	if err := processInputs(sources, io.Discard, config); err != nil {
+		t.Fatalf("processInputs() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("trace events were not written: %v", err)
+	}
+	var file traceFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("invalid trace JSON: %v", err)
+	}
+
+	processes := map[int]string{}
+	for _, e := range file.TraceEvents {
+		if e.Name == "process_name" {
+			processes[e.Pid], _ = e.Args["name"].(string)
+		}
+	}
+	if processes[1] != "claude s1" || processes[2] != "claude web" {
+		t.Errorf("processes = %v, want one per session", processes)
+	}
+}
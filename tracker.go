@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Tracker はセッション中のモデルのターンとツール呼び出しを到着時刻付きで追跡する
+type Tracker struct {
+	Steps  []*Step        // 発生順のステップ
+	Start  time.Time      // 最初の行の到着時刻
+	End    time.Time      // 最後の行の到着時刻
+	Init   *SystemMessage // system (init) メッセージ
+	Result *ResultMessage // 最後に受信した result メッセージ
+
+	calls map[string]*Step     // tool_use_id → ツール呼び出し
+	turns map[string]*Step     // message id → ターン
+	ready map[string]time.Time // parent_tool_use_id ごとの、モデルに制御が移った時刻
+}
+
+// Step はタイムライン上の1ステップ (モデルのターンまたはツール呼び出し)
+type Step struct {
+	Kind     string          // "turn" or "tool"
+	ID       string          // message id または tool_use_id
+	Name     string          // モデル名またはツール名
+	Input    json.RawMessage // ツールの入力
+	ParentID string          // サブエージェント内のステップの場合の parent_tool_use_id
+	Start    time.Time
+	End      time.Time
+	Done     bool // ターンの受信完了、またはツール結果を受信済み
+	IsError  bool
+	Result   string
+	Usage    Usage  // ターンのトークン使用量
+	Text     string // ターンで出力されたテキスト
+
+	turn *Step // ツール呼び出しを行ったターン
+}
+
+// Duration はステップの所要時間
+func (s *Step) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// newTracker は空の Tracker を作成
+func newTracker() *Tracker {
+	return &Tracker{
+		calls: make(map[string]*Step),
+		turns: make(map[string]*Step),
+		ready: make(map[string]time.Time),
+	}
+}
+
+// observe は1行分のメッセージを到着時刻 at とともに記録
+func (t *Tracker) observe(msgType string, data []byte, at time.Time) {
+	if t.Start.IsZero() {
+		t.Start = at
+	}
+	t.End = at
+
+	switch msgType {
+	case "system":
+		var msg SystemMessage
+		if err := json.Unmarshal(data, &msg); err == nil && msg.Subtype == "init" {
+			t.Init = &msg
+		}
+		t.ready[""] = at
+	case "assistant":
+		var msg AssistantMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return
+		}
+		t.observeAssistant(msg, at)
+	case "user":
+		var msg UserMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return
+		}
+		t.observeUser(msg, at)
+	case "result":
+		var msg ResultMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return
+		}
+		t.Result = &msg
+	}
+}
+
+// observeAssistant はターンとツール呼び出しの開始を記録
+func (t *Tracker) observeAssistant(msg AssistantMessage, at time.Time) {
+	parent := msg.ParentToolUseID
+
+	// 同じ message id のチャンクは1つのターンにまとめる
+	turn := t.turns[msg.Message.ID]
+	if turn == nil || msg.Message.ID == "" {
+		turn = &Step{
+			Kind:     "turn",
+			ID:       msg.Message.ID,
+			Name:     msg.Message.Model,
+			ParentID: parent,
+			Start:    t.readyAt(parent, at),
+		}
+		t.Steps = append(t.Steps, turn)
+		if msg.Message.ID != "" {
+			t.turns[msg.Message.ID] = turn
+		}
+	}
+	turn.End = at
+	turn.Done = true
+	turn.Usage = msg.Message.Usage
+
+	for _, content := range msg.Message.Content {
+		if content.Type == "text" {
+			turn.Text = joinNonEmpty("\n\n", turn.Text, content.Text)
+		}
+		if content.Type != "tool_use" {
+			continue
+		}
+		call := &Step{
+			Kind:     "tool",
+			ID:       content.ID,
+			Name:     content.Name,
+			Input:    content.Input,
+			ParentID: parent,
+			Start:    at,
+			End:      at,
+			turn:     turn,
+		}
+		t.Steps = append(t.Steps, call)
+		t.calls[content.ID] = call
+	}
+
+	t.ready[parent] = at
+}
+
+// observeUser はツール呼び出しの完了を記録
+func (t *Tracker) observeUser(msg UserMessage, at time.Time) {
+	for _, result := range msg.Message.Content {
+		if result.Type != "tool_result" {
+			continue
+		}
+		call := t.calls[result.ToolUseID]
+		if call == nil {
+			continue
+		}
+		call.End = at
+		call.Done = true
+		call.IsError = result.IsError
+		call.Result = result.Content
+	}
+
+	t.ready[msg.ParentToolUseID] = at
+}
+
+// close はセッション終了時に呼び、結果が届かなかったツール呼び出しの終了時刻を確定する
+func (t *Tracker) close() {
+	for _, step := range t.Steps {
+		if step.Kind == "tool" && !step.Done {
+			step.End = t.End
+		}
+	}
+}
+
+// readyAt はモデルに制御が移った時刻を返す
+// サブエージェントの最初のターンは呼び出し元の Task の開始時刻から数える
+func (t *Tracker) readyAt(parent string, at time.Time) time.Time {
+	if ready, ok := t.ready[parent]; ok {
+		return ready
+	}
+	if call := t.calls[parent]; call != nil {
+		return call.Start
+	}
+	return at
+}
+
+// SessionID はセッションの session_id を返す (result を優先)
+func (t *Tracker) SessionID() string {
+	if t.Result != nil && t.Result.SessionID != "" {
+		return t.Result.SessionID
+	}
+	if t.Init != nil {
+		return t.Init.SessionID
+	}
+	return ""
+}
+
+// Latency は tool_use から対応する tool_result までの時間を返す
+func (t *Tracker) Latency(toolUseID string) (time.Duration, bool) {
+	call := t.calls[toolUseID]
+	if call == nil || !call.Done {
+		return 0, false
+	}
+	return call.Duration(), true
+}
+
+// Call は tool_use_id に対応するツール呼び出しを返す
+func (t *Tracker) Call(toolUseID string) *Step {
+	return t.calls[toolUseID]
+}
+
+// children はターンが行ったツール呼び出し、または Task が起動したサブエージェントのターンを返す
+func (t *Tracker) children(parent *Step) []*Step {
+	var children []*Step
+	for _, step := range t.Steps {
+		switch parent.Kind {
+		case "turn":
+			if step.turn == parent {
+				children = append(children, step)
+			}
+		case "tool":
+			if step.Kind == "turn" && step.ParentID == parent.ID {
+				children = append(children, step)
+			}
+		}
+	}
+	return children
+}
+
+// joinNonEmpty は空でない要素を sep で連結
+func joinNonEmpty(sep string, items ...string) string {
+	var parts []string
+	for _, item := range items {
+		if item != "" {
+			parts = append(parts, item)
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// formatLatency はレイテンシ表示用に時間をフォーマット
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("(%.1fs)", d.Seconds())
+}
+
+// formatTimeline は所要時間の長い順にステップを並べたタイムラインをフォーマット
+func formatTimeline(t *Tracker, config *FilterConfig) string {
+	if len(t.Steps) == 0 {
+		return ""
+	}
+
+	steps := make([]*Step, len(t.Steps))
+	copy(steps, t.Steps)
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].Duration() > steps[j].Duration()
+	})
+
+	// standard モードでは上位のみ表示
+	maxSteps := 10
+	if config.InfoLevel == "verbose" {
+		maxSteps = len(steps)
+	}
+	omitted := 0
+	if len(steps) > maxSteps {
+		omitted = len(steps) - maxSteps
+		steps = steps[:maxSteps]
+	}
+
+	var output strings.Builder
+	output.WriteString("\n")
+	output.WriteString(colorize("Timeline (slowest first):", roleMetrics, config.colors()))
+	output.WriteString("\n")
+
+	for _, step := range steps {
+		duration := fmt.Sprintf("%7.1fs", step.Duration().Seconds())
+		offset := fmt.Sprintf("+%.1fs", step.Start.Sub(t.Start).Seconds())
+		output.WriteString(fmt.Sprintf("  %s  %-8s  %s\n", duration, offset, describeStep(step, config)))
+	}
+
+	if omitted > 0 {
+		output.WriteString(colorize(fmt.Sprintf("  ... (%d more steps)", omitted), roleMuted, config.colors()))
+		output.WriteString("\n")
+	}
+
+	return output.String()
+}
+
+// describeStep はタイムラインの1行分の説明を作成
+func describeStep(step *Step, config *FilterConfig) string {
+	var desc string
+	switch step.Kind {
+	case "turn":
+		desc = colorize("turn", roleMuted, config.colors())
+		if step.Name != "" {
+			desc += " " + sanitizeText(step.Name, config)
+		}
+	case "tool":
+		desc = colorize(sanitizeText(step.Name, config), toolRole(step.Name), config.colors())
+		if params := extractMainParams(step.Name, step.Input); params != "" {
+			desc += ": " + params
+		}
+		if !step.Done {
+			desc += " " + colorize("(no result)", roleMuted, config.colors())
+		} else if step.IsError {
+			desc += " " + colorize("(error)", roleError, config.colors())
+		}
+	}
+
+	// サブエージェント内のステップ
+	if step.ParentID != "" {
+		desc = "↳ " + desc
+	}
+	return desc
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// trackLines は各行を1秒刻みの到着時刻とみなして Tracker に記録する
+func trackLines(t *testing.T, offsets []int, lines []string) *Tracker {
+	t.Helper()
+	base := time.Date(2025, 10, 18, 10, 0, 0, 0, time.UTC)
+	tracker := newTracker()
+	for i, line := range lines {
+		msgType, err := parseMessageType(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tracker.observe(msgType, []byte(line), base.Add(time.Duration(offsets[i])*time.Second))
+	}
+	tracker.close()
+	return tracker
+}
+
+func TestTracker_ToolLatency(t *testing.T) {
+	tracker := trackLines(t, []int{0, 2, 2, 5, 6, 9}, []string{
+		`{"type":"system","subtype":"init"}`,
+		`{"type":"assistant","message":{"id":"m1","model":"sonnet","content":[{"type":"text","text":"Running"}]}}`,
+		`{"type":"assistant","message":{"id":"m1","model":"sonnet","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"sleep 3"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}}`,
+		`{"type":"assistant","message":{"id":"m2","model":"sonnet","content":[{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"a.go"}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t2","content":"denied","is_error":true}]}}`,
+	})
+
+	if got, ok := tracker.Latency("t1"); !ok || got != 3*time.Second {
+		t.Errorf("Latency(t1) = %v, %v, want 3s", got, ok)
+	}
+	if got, ok := tracker.Latency("t2"); !ok || got != 3*time.Second {
+		t.Errorf("Latency(t2) = %v, %v, want 3s", got, ok)
+	}
+	if _, ok := tracker.Latency("unknown"); ok {
+		t.Error("Latency(unknown) should not be found")
+	}
+
+	// m1: init(0s) → 最後のチャンク(2s), m2: tool_result(5s) → 6s
+	var turns []time.Duration
+	for _, step := range tracker.Steps {
+		if step.Kind == "turn" {
+			turns = append(turns, step.Duration())
+		}
+	}
+	if len(turns) != 2 || turns[0] != 2*time.Second || turns[1] != time.Second {
+		t.Errorf("turn durations = %v, want [2s 1s]", turns)
+	}
+}
+
+func TestTracker_Subagent(t *testing.T) {
+	tracker := trackLines(t, []int{0, 1, 4, 6, 10}, []string{
+		`{"type":"system","subtype":"init"}`,
+		`{"type":"assistant","message":{"id":"m1","content":[{"type":"tool_use","id":"task1","name":"Task","input":{"description":"explore"}}]}}`,
+		`{"type":"assistant","message":{"id":"s1","content":[{"type":"tool_use","id":"t1","name":"Glob","input":{"pattern":"*.go"}}]},"parent_tool_use_id":"task1"}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"a.go"}]},"parent_tool_use_id":"task1"}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"task1","content":[{"type":"text","text":"found a.go"}]}]}}`,
+	})
+
+	var subTurn *Step
+	for _, step := range tracker.Steps {
+		if step.Kind == "turn" && step.ParentID == "task1" {
+			subTurn = step
+		}
+	}
+	if subTurn == nil {
+		t.Fatal("subagent turn not tracked")
+	}
+	// サブエージェントの最初のターンは Task の開始(1s)から数える
+	if subTurn.Duration() != 3*time.Second {
+		t.Errorf("subagent turn duration = %v, want 3s", subTurn.Duration())
+	}
+	if got, ok := tracker.Latency("task1"); !ok || got != 9*time.Second {
+		t.Errorf("Latency(task1) = %v, %v, want 9s", got, ok)
+	}
+}
+
+func TestTracker_UnfinishedCall(t *testing.T) {
+	tracker := trackLines(t, []int{0, 1, 7}, []string{
+		`{"type":"assistant","message":{"id":"m1","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"make"}}]}}`,
+		`{"type":"assistant","message":{"id":"m2","content":[{"type":"text","text":"waiting"}]}}`,
+		`{"type":"assistant","message":{"id":"m3","content":[{"type":"text","text":"still waiting"}]}}`,
+	})
+
+	if _, ok := tracker.Latency("t1"); ok {
+		t.Error("Latency() should not be reported for an unfinished call")
+	}
+	if got := tracker.Steps[1].Duration(); got != 7*time.Second {
+		t.Errorf("unfinished call duration = %v, want 7s", got)
+	}
+}
+
+func TestFormatTimeline(t *testing.T) {
+	tracker := trackLines(t, []int{0, 1, 1, 4, 5, 15}, []string{
+		`{"type":"system","subtype":"init"}`,
+		`{"type":"assistant","message":{"id":"m1","model":"sonnet","content":[{"type":"tool_use","id":"t1","name":"Glob","input":{"pattern":"*.go"}}]}}`,
+		`{"type":"assistant","message":{"id":"m1","model":"sonnet","content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"go test ./..."}}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"a.go"}]}}`,
+		`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t2","content":"FAIL","is_error":true}]}}`,
+		`{"type":"assistant","message":{"id":"m2","model":"sonnet","content":[{"type":"text","text":"done"}]}}`,
+	})
+
+	got := formatTimeline(tracker, &FilterConfig{InfoLevel: "standard", UseColor: false})
+
+	want := []string{
+		"Timeline (slowest first):",
+		"     10.0s  +5.0s     turn sonnet",
+		"      4.0s  +1.0s     Bash: command=\"go test ./...\" (error)",
+		"      3.0s  +1.0s     Glob: pattern=\"*.go\"",
+		"      1.0s  +0.0s     turn sonnet",
+	}
+	lines := strings.Split(strings.TrimPrefix(got, "\n"), "\n")
+	lines = lines[:len(lines)-1]
+	if len(lines) != len(want) {
+		t.Fatalf("formatTimeline() =\n%s", got)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %q, want %q", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestFormatTimeline_Empty(t *testing.T) {
+	if got := formatTimeline(newTracker(), &FilterConfig{}); got != "" {
+		t.Errorf("formatTimeline() = %q, want empty", got)
+	}
+}
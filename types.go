@@ -1,37 +1,57 @@
 package main
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"strings"
+)
 
 // Message はClaude CLIが出力するJSONメッセージの基本構造
 type Message struct {
-	Type string `json:"type"`
+	Type      string `json:"type"`
+	SessionID string `json:"session_id,omitempty"`
 }
 
 // SystemMessage はsystemタイプのメッセージ
 type SystemMessage struct {
-	Type              string `json:"type"`
-	Subtype           string `json:"subtype"`
-	Cwd               string `json:"cwd"`
-	SessionID         string `json:"session_id"`
-	Model             string `json:"model"`
-	ClaudeCodeVersion string `json:"claude_code_version"`
+	Type              string   `json:"type"`
+	Subtype           string   `json:"subtype"`
+	Cwd               string   `json:"cwd"`
+	SessionID         string   `json:"session_id"`
+	Model             string   `json:"model"`
+	ClaudeCodeVersion string   `json:"claude_code_version"`
+	Tools             []string `json:"tools"`
 }
 
 // AssistantMessage はassistantタイプのメッセージ
 type AssistantMessage struct {
 	Type    string `json:"type"`
 	Message struct {
+		ID      string    `json:"id"`
+		Model   string    `json:"model"`
 		Content []Content `json:"content"`
+		Usage   Usage     `json:"usage"`
 	} `json:"message"`
+	ParentToolUseID string `json:"parent_tool_use_id"` // サブエージェントの場合は呼び出し元の Task の id
+}
+
+// Usage はトークン使用量
+type Usage struct {
+	InputTokens              int `json:"input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
 }
 
 // Content はassistantメッセージのコンテンツ
 type Content struct {
-	Type string `json:"type"` // "text" or "tool_use"
+	Type string `json:"type"` // "text", "thinking" or "tool_use"
 
 	// text タイプの場合
 	Text string `json:"text,omitempty"`
 
+	// thinking タイプの場合
+	Thinking string `json:"thinking,omitempty"`
+
 	// tool_use タイプの場合
 	ID    string          `json:"id,omitempty"`
 	Name  string          `json:"name,omitempty"`
@@ -45,6 +65,7 @@ type UserMessage struct {
 		Role    string       `json:"role"`
 		Content []ToolResult `json:"content"`
 	} `json:"message"`
+	ParentToolUseID string `json:"parent_tool_use_id"`
 }
 
 // ToolResult はツール実行結果
@@ -55,6 +76,42 @@ type ToolResult struct {
 	IsError   bool   `json:"is_error,omitempty"`
 }
 
+// UnmarshalJSON は content が文字列でもコンテンツブロックの配列 (Task の結果など) でも受け付ける
+func (r *ToolResult) UnmarshalJSON(data []byte) error {
+	type plain ToolResult
+	var raw struct {
+		plain
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*r = ToolResult(raw.plain)
+
+	if len(raw.Content) == 0 || string(raw.Content) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw.Content, &r.Content); err == nil {
+		return nil
+	}
+
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw.Content, &blocks); err != nil {
+		return err
+	}
+	var texts []string
+	for _, block := range blocks {
+		if block.Type == "text" {
+			texts = append(texts, block.Text)
+		}
+	}
+	r.Content = strings.Join(texts, "\n")
+	return nil
+}
+
 // ResultMessage はresultタイプのメッセージ (最終結果とメトリクス)
 type ResultMessage struct {
 	Type         string  `json:"type"`
@@ -65,4 +122,25 @@ type ResultMessage struct {
 	TotalCostUsd float64 `json:"total_cost_usd"`
 	NumTurns     int     `json:"num_turns"`
 	SessionID    string  `json:"session_id"`
+
+	DurationAPIMs     int                   `json:"duration_api_ms"`
+	Usage             Usage                 `json:"usage"`
+	ModelUsage        map[string]ModelUsage `json:"modelUsage"`
+	PermissionDenials []PermissionDenial    `json:"permission_denials"`
+}
+
+// ModelUsage は result の modelUsage に含まれるモデルごとの使用量
+type ModelUsage struct {
+	InputTokens              int     `json:"inputTokens"`
+	OutputTokens             int     `json:"outputTokens"`
+	CacheReadInputTokens     int     `json:"cacheReadInputTokens"`
+	CacheCreationInputTokens int     `json:"cacheCreationInputTokens"`
+	CostUSD                  float64 `json:"costUSD"`
+}
+
+// PermissionDenial はパーミッションが拒否されたツール呼び出し
+type PermissionDenial struct {
+	ToolName  string          `json:"tool_name"`
+	ToolUseID string          `json:"tool_use_id"`
+	ToolInput json.RawMessage `json:"tool_input"`
 }
@@ -272,6 +272,46 @@ func TestParseUserMessage_ToolResult(t *testing.T) {
 	}
 }
 
+// TestParseToolResult_ContentBlocks は配列形式の content を連結した文字列としてパースできることを確認する
+func TestParseToolResult_ContentBlocks(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantContent string
+	}{
+		{
+			name:        "string content",
+			input:       `{"type":"tool_result","tool_use_id":"t1","content":"plain"}`,
+			wantContent: "plain",
+		},
+		{
+			name:        "text blocks",
+			input:       `{"type":"tool_result","tool_use_id":"t1","content":[{"type":"text","text":"first"},{"type":"image"},{"type":"text","text":"second"}]}`,
+			wantContent: "first\nsecond",
+		},
+		{
+			name:        "no content",
+			input:       `{"type":"tool_result","tool_use_id":"t1"}`,
+			wantContent: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result ToolResult
+			if err := json.Unmarshal([]byte(tt.input), &result); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if result.ToolUseID != "t1" {
+				t.Errorf("ToolResult.ToolUseID = %v, want t1", result.ToolUseID)
+			}
+			if result.Content != tt.wantContent {
+				t.Errorf("ToolResult.Content = %q, want %q", result.Content, tt.wantContent)
+			}
+		})
+	}
+}
+
 // TestParseResultMessage はresultメッセージを正しくパースできることを確認する
 func TestParseResultMessage(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultMaxLineWidth は standard/minimal モードでツール結果の1行に表示する最大の幅 (セル数)
+const defaultMaxLineWidth = 500
+
+// wideRanges は East Asian Width が W (Wide) または F (Fullwidth) の主な範囲
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F}, {0x231A, 0x231B}, {0x2329, 0x232A}, {0x23E9, 0x23EC}, {0x23F0, 0x23F0}, {0x23F3, 0x23F3},
+	{0x25FD, 0x25FE}, {0x2614, 0x2615}, {0x2648, 0x2653}, {0x267F, 0x267F}, {0x2693, 0x2693}, {0x26A1, 0x26A1},
+	{0x26AA, 0x26AB}, {0x26BD, 0x26BE}, {0x26C4, 0x26C5}, {0x26CE, 0x26CE}, {0x26D4, 0x26D4}, {0x26EA, 0x26EA},
+	{0x26F2, 0x26F3}, {0x26F5, 0x26F5}, {0x26FA, 0x26FA}, {0x26FD, 0x26FD}, {0x2705, 0x2705}, {0x270A, 0x270B},
+	{0x2728, 0x2728}, {0x274C, 0x274C}, {0x274E, 0x274E}, {0x2753, 0x2755}, {0x2757, 0x2757}, {0x2795, 0x2797},
+	{0x27B0, 0x27B0}, {0x27BF, 0x27BF}, {0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55},
+	{0x2E80, 0x303E}, {0x3041, 0x33FF}, {0x3400, 0x4DBF}, {0x4E00, 0x9FFF}, {0xA000, 0xA4CF}, {0xA960, 0xA97F},
+	{0xAC00, 0xD7A3}, {0xF900, 0xFAFF}, {0xFE10, 0xFE19}, {0xFE30, 0xFE6F}, {0xFF00, 0xFF60}, {0xFFE0, 0xFFE6},
+	{0x16FE0, 0x16FE4}, {0x17000, 0x18AFF}, {0x1B000, 0x1B2FF}, {0x1F004, 0x1F004}, {0x1F0CF, 0x1F0CF},
+	{0x1F18E, 0x1F18E}, {0x1F191, 0x1F19A}, {0x1F200, 0x1F251}, {0x1F300, 0x1F64F}, {0x1F680, 0x1F6FF},
+	{0x1F7E0, 0x1F7EB}, {0x1F900, 0x1F9FF}, {0x1FA70, 0x1FAFF}, {0x20000, 0x2FFFD}, {0x30000, 0x3FFFD},
+}
+
+// runeWidth は文字の表示幅 (セル数) を返す
+// 全角・絵文字は2、結合文字・ゼロ幅文字・制御文字は0
+func runeWidth(r rune) int {
+	if r < 0x20 || (r >= 0x7F && r < 0xA0) {
+		return 0
+	}
+	if r < 0x1100 {
+		if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+			return 0
+		}
+		return 1
+	}
+	for _, rg := range wideRanges {
+		if r < rg[0] {
+			break
+		}
+		if r <= rg[1] {
+			return 2
+		}
+	}
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	return 1
+}
+
+// escapeLength は s の先頭が ANSI エスケープシーケンス (CSI または OSC) の場合にその長さを返す
+func escapeLength(s string) int {
+	if len(s) < 2 || s[0] != '\x1b' {
+		return 0
+	}
+	switch s[1] {
+	case '[':
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7E {
+				return i + 1
+			}
+		}
+	case ']':
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+	}
+	return 0
+}
+
+// displayWidth は文字列の表示幅を返す (ANSI エスケープシーケンスは幅0として扱う)
+func displayWidth(s string) int {
+	width := 0
+	for s != "" {
+		if n := escapeLength(s); n > 0 {
+			s = s[n:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		width += runeWidth(r)
+		s = s[size:]
+	}
+	return width
+}
+
+// capLine は表示幅が maxWidth を超える行を切り詰め、省略した文字数を付ける
+// maxWidth が0以下の場合は切り詰めない
+func capLine(line string, maxWidth int) string {
+	if maxWidth <= 0 || displayWidth(line) <= maxWidth {
+		return line
+	}
+	head, rest := splitWidth(line, maxWidth)
+	return head + fmt.Sprintf("… (%d more chars)", utf8.RuneCountInString(rest))
+}
+
+// capLines は各行を capLine で切り詰める
+func capLines(s string, maxWidth int) string {
+	if maxWidth <= 0 {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = capLine(line, maxWidth)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateWidth は1行を表示幅 width に収まるように切り詰める (切り詰めた場合は末尾を "…" にする)
+func truncateWidth(line string, width int) string {
+	if width <= 0 || displayWidth(line) <= width {
+		return line
+	}
+	head, _ := splitWidth(line, width-1)
+	return head + "…"
+}
+
+// splitWidth は表示幅 width に収まる先頭部分と残りに分ける
+// 全角文字の途中では分けない
+func splitWidth(s string, width int) (string, string) {
+	used := 0
+	for i := 0; i < len(s); {
+		if n := escapeLength(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w := runeWidth(r)
+		if used+w > width {
+			return s[:i], s[i:]
+		}
+		used += w
+		i += size
+	}
+	return s, ""
+}
+
+// wrapText は各行を表示幅 width で折り返す
+// 英数字の単語の途中ではなるべく折り返さず、色は折り返した行でも続くように付け直す
+// width が0以下の場合は折り返さない
+func wrapText(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	var output []string
+	for _, line := range lines {
+		output = append(output, wrapLine(line, width)...)
+	}
+	return strings.Join(output, "\n")
+}
+
+// wrapLine は1行を表示幅 width で折り返す
+func wrapLine(line string, width int) []string {
+	if displayWidth(line) <= width {
+		return []string{line}
+	}
+
+	var rows []string
+	var active string // 現在有効な SGR (折り返した行の先頭で付け直す)
+	for displayWidth(line) > width {
+		head, rest := splitWidth(line, width)
+		if head == "" {
+			break // width より幅の広い文字しか残っていない
+		}
+		// 単語の途中で分けない (行内に空白があり、次の文字も英数字の場合)
+		if isWordRune(firstRune(rest)) {
+			if i := strings.LastIndexByte(head, ' '); i > 0 && displayWidth(head[:i]) > width/2 {
+				head, rest = head[:i], head[i+1:]+rest
+			}
+		}
+		row := active + head
+		active = activeSGR(active, head)
+		if active != "" {
+			row += ColorReset
+		}
+		rows = append(rows, row)
+		line = rest
+	}
+	return append(rows, active+line)
+}
+
+// activeSGR は active の状態から text の SGR を適用した後に有効な SGR を返す
+func activeSGR(active, text string) string {
+	for i := 0; i < len(text); {
+		n := escapeLength(text[i:])
+		if n == 0 {
+			i++
+			continue
+		}
+		seq := text[i : i+n]
+		if strings.HasSuffix(seq, "m") {
+			if seq == ColorReset || seq == "\x1b[m" {
+				active = ""
+			} else {
+				active += seq
+			}
+		}
+		i += n
+	}
+	return active
+}
+
+// firstRune は文字列の先頭の文字を返す (エスケープシーケンスは飛ばす)
+func firstRune(s string) rune {
+	for s != "" {
+		if n := escapeLength(s); n > 0 {
+			s = s[n:]
+			continue
+		}
+		r, _ := utf8.DecodeRuneInString(s)
+		return r
+	}
+	return 0
+}
+
+// isWordRune は英数字の単語を構成する文字かどうかを判定
+func isWordRune(r rune) bool {
+	return r < 0x1100 && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
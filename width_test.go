@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want int
+	}{
+		{name: "ascii", s: "hello", want: 5},
+		{name: "japanese", s: "日本語のテスト", want: 14},
+		{name: "mixed", s: "Go言語", want: 6},
+		{name: "halfwidth katakana", s: "ｶﾀｶﾅ", want: 4},
+		{name: "fullwidth ascii", s: "ＡＢＣ", want: 6},
+		{name: "hangul", s: "한국어", want: 6},
+		{name: "emoji", s: "✅ done 🚀", want: 10},
+		{name: "combining mark", s: "é", want: 1},
+		{name: "zero width joiner", s: "a‍b", want: 2},
+		{name: "ansi colors", s: ColorRed + "エラー" + ColorReset, want: 6},
+		{name: "osc", s: "\x1b]0;title\x07ok", want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := displayWidth(tt.s); got != tt.want {
+				t.Errorf("displayWidth(%q) = %d, want %d", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		maxWidth int
+		want     string
+	}{
+		{name: "short", line: "abc", maxWidth: 5, want: "abc"},
+		{name: "ascii", line: strings.Repeat("x", 12), maxWidth: 5, want: "xxxxx… (7 more chars)"},
+		{name: "japanese counts two cells", line: "あいうえおかきくけこ", maxWidth: 6, want: "あいう… (7 more chars)"},
+		{name: "does not split wide char", line: "aあいう", maxWidth: 4, want: "aあ… (2 more chars)"},
+		{name: "disabled", line: strings.Repeat("x", 12), maxWidth: 0, want: strings.Repeat("x", 12)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := capLine(tt.line, tt.maxWidth); got != tt.want {
+				t.Errorf("capLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateWidth(t *testing.T) {
+	tests := []struct {
+		line  string
+		width int
+		want  string
+	}{
+		{line: "hello world", width: 20, want: "hello world"},
+		{line: "hello world", width: 6, want: "hello…"},
+		{line: "こんにちは世界", width: 7, want: "こんに…"},
+		{line: "こんにちは世界", width: 8, want: "こんに…"},
+	}
+
+	for _, tt := range tests {
+		if got := truncateWidth(tt.line, tt.width); got != tt.want {
+			t.Errorf("truncateWidth(%q, %d) = %q, want %q", tt.line, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		width int
+		want  string
+	}{
+		{name: "fits", s: "short line", width: 20, want: "short line"},
+		{name: "words", s: "the quick brown fox jumps", width: 10, want: "the quick\nbrown fox\njumps"},
+		{name: "long word is split", s: strings.Repeat("a", 25), width: 10, want: "aaaaaaaaaa\naaaaaaaaaa\naaaaa"},
+		{name: "japanese", s: "日本語の文章を折り返す", width: 8, want: "日本語の\n文章を折\nり返す"},
+		{name: "wide char at edge", s: "abcあい", width: 4, want: "abc\nあい"},
+		{name: "keeps newlines", s: "ab\ncdef", width: 2, want: "ab\ncd\nef"},
+		{name: "disabled", s: strings.Repeat("a", 25), width: 0, want: strings.Repeat("a", 25)},
+		{
+			name:  "color continues on next row",
+			s:     ColorGray + "abcdef" + ColorReset + " x",
+			width: 4,
+			want:  ColorGray + "abcd" + ColorReset + "\n" + ColorGray + "ef" + ColorReset + " x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrapText(tt.s, tt.width); got != tt.want {
+				t.Errorf("wrapText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatToolResult_Width(t *testing.T) {
+	long := `{"items":[` + strings.Repeat(`"x",`, 5000) + `"y"]}`
+
+	standard := formatToolResult(ToolResult{Content: long}, &FilterConfig{InfoLevel: "standard", MaxLineWidth: -1})
+	if len(standard) > defaultMaxLineWidth+100 {
+		t.Errorf("standard output should be capped, got %d bytes", len(standard))
+	}
+	if !strings.Contains(standard, "… (19515 more chars)") {
+		t.Errorf("missing omitted chars marker: %q", standard[len(standard)-40:])
+	}
+
+	verbose := formatToolResult(ToolResult{Content: long}, &FilterConfig{InfoLevel: "verbose", MaxLineWidth: -1})
+	if !strings.Contains(verbose, `"y"]}`) {
+		t.Error("verbose output should not be capped")
+	}
+
+	minimal := formatToolResult(ToolResult{Content: "ファイルを読み込みました\n2行目"}, &FilterConfig{InfoLevel: "minimal", Width: 12})
+	if want := "← ファイル…\n"; minimal != want {
+		t.Errorf("minimal = %q, want %q", minimal, want)
+	}
+}